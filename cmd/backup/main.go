@@ -0,0 +1,90 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"flashcards/internal/backup"
+	"flashcards/internal/filestore"
+	"flashcards/internal/gcp"
+	"flashcards/internal/pgstore"
+	"flashcards/internal/platform"
+	"flashcards/internal/sqlstore"
+)
+
+var postgresURL = flag.String("postgres", os.Getenv("DATABASE_URL"), "Postgres connection URL of the database to back up or restore to")
+var sqlitePath = flag.String("sqlite", os.Getenv("SQLITE_DATABASE"), "SQLite database file to back up or restore to")
+var dataDir = flag.String("data", os.Getenv("FLASHCARDS_DATA"), "Directory of JSON files to back up or restore to")
+var restore = flag.Bool("restore", false, "Load the archive into the data store, instead of writing the data store to the archive")
+var existing = flag.String("existing", backup.SKIP_EXISTING, "What a restore does with decks and author keys already in the data store, skip or overwrite")
+
+// main backs up the decks and author keys of a data store to a zip archive, or restores them from one.
+// Without -postgres, -sqlite or -data it uses Firestore, in the project given by $GCLOUD_PROJECT.
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] ARCHIVE\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	archivePath := flag.Arg(0)
+
+	ctx := platform.NewStartupContext()
+	p := getPlatform(ctx)
+	if p == nil {
+		fmt.Fprintln(os.Stderr, "No data store given, use -postgres, -sqlite, -data or set GCLOUD_PROJECT for Firestore")
+		os.Exit(2)
+	}
+	logs := p.Logger()
+	store := p.DataStore()
+	store.Init(ctx)
+
+	var summary backup.Summary
+	var err error
+	if *restore {
+		var archive *zip.ReadCloser
+		archive, err = zip.OpenReader(archivePath)
+		if err == nil {
+			summary, err = backup.Restore(ctx, store, &archive.Reader, *existing)
+			archive.Close()
+		}
+	} else {
+		var out *os.File
+		out, err = os.Create(archivePath)
+		if err == nil {
+			summary, err = backup.Backup(ctx, store, out)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+
+	if err != nil {
+		logs.Error(ctx, "Failed after %v: %v", summary, err)
+		os.Exit(1)
+	}
+	if *restore {
+		logs.Info(ctx, "Restored %v from %s to %s", summary, archivePath, store.Summary())
+	} else {
+		logs.Info(ctx, "Backed up %v from %s to %s", summary, store.Summary(), archivePath)
+	}
+}
+
+func getPlatform(ctx context.Context) platform.Platform {
+	if *postgresURL != "" {
+		return pgstore.PostgresPlatform(ctx, *postgresURL)
+	} else if *sqlitePath != "" {
+		return sqlstore.SqlitePlatform(ctx, *sqlitePath)
+	} else if *dataDir != "" {
+		return filestore.FilePlatform(ctx, *dataDir)
+	} else if gcp.RunningOnGCloud() {
+		return gcp.GcpPlatform(ctx)
+	}
+	return nil
+}
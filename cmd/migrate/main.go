@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"flashcards/internal/filestore"
+	"flashcards/internal/gcp"
+	"flashcards/internal/platform"
+	"flashcards/internal/schema"
+)
+
+var dataDir = flag.String("data", os.Getenv("FLASHCARDS_DATA"), "Directory of JSON files to upgrade")
+
+// main upgrades every stored deck and card to the current schema version. This is optional, as
+// documents are also upgraded when they are read, but saves upgrading them again on every read.
+// Without -data it uses Firestore, in the project given by $GCLOUD_PROJECT. SQLite databases
+// upgrade their tables when they are opened, so need no batch upgrade.
+func main() {
+	flag.Parse()
+
+	ctx := platform.NewStartupContext()
+	p := getPlatform(ctx)
+	if p == nil {
+		fmt.Fprintln(os.Stderr, "No data store given, use -data or set GCLOUD_PROJECT for Firestore")
+		os.Exit(2)
+	}
+	logs := p.Logger()
+	store := p.DataStore()
+	store.Init(ctx)
+
+	upgrader, ok := store.(platform.SchemaUpgrader)
+	if !ok {
+		logs.Error(ctx, "%s cannot be upgraded in a batch", store.Summary())
+		os.Exit(1)
+	}
+	for _, migration := range schema.Migrations() {
+		logs.Info(ctx, "Schema version %d: %s", migration.Version, migration.Description)
+	}
+	count, err := upgrader.UpgradeSchema(ctx)
+	if err != nil {
+		logs.Error(ctx, "Failed after upgrading %d documents: %v", count, err)
+		os.Exit(1)
+	}
+	logs.Info(ctx, "Upgraded %d documents in %s to schema version %d", count, store.Summary(), schema.CurrentVersion())
+}
+
+func getPlatform(ctx context.Context) platform.Platform {
+	if *dataDir != "" {
+		return filestore.FilePlatform(ctx, *dataDir)
+	} else if gcp.RunningOnGCloud() {
+		return gcp.GcpPlatform(ctx)
+	}
+	return nil
+}
@@ -2,17 +2,33 @@ package main
 
 import (
 	"context"
+	"flag"
 	"net/http"
 	"os"
+	"time"
 
+	"flashcards/internal/filestore"
 	"flashcards/internal/gcp"
 	"flashcards/internal/handlers"
+	"flashcards/internal/pgstore"
 	"flashcards/internal/platform"
+	"flashcards/internal/sqlstore"
 	"flashcards/internal/test"
 )
 
+var postgresURL = flag.String("postgres", os.Getenv("DATABASE_URL"), "Postgres connection URL of the database to keep flashcards in, instead of Firestore or memory")
+var sqlitePath = flag.String("sqlite", os.Getenv("SQLITE_DATABASE"), "SQLite database file to keep flashcards in, instead of Firestore or memory")
+var dataDir = flag.String("data", os.Getenv("FLASHCARDS_DATA"), "Directory to keep flashcards in as JSON files, instead of in memory")
+var authorKey = flag.String("author-key", "", "Author key to add to a Postgres, SQLite or file data store, which authors can register with as an invite code")
+
+// The deck cache is off unless asked for, as each server has its own and with several servers one
+// would show decks changed on another as they were before, and report spurious edit conflicts
+var cacheTTL = flag.Duration("cache-ttl", envDuration("DECK_CACHE_TTL", 0), "How long to keep decks in memory after reading them, such as 30s, or 0 to always read them from the data store")
+var cacheSize = flag.Int("cache-size", 500, "Most decks to keep in memory at once")
+
 // main starts an http server on the $PORT environment variable.
 func main() {
+	flag.Parse()
 	ctx := platform.NewStartupContext()
 
 	p := getPlatform(ctx)
@@ -22,11 +38,21 @@ func main() {
 	//logEnvironment(logs, ctx)
 
 	p.DataStore().Init(ctx)
-	if p.DataStore().Summary() == "TestDataStore" {
+	if keys, ok := p.DataStore().(platform.AuthorKeyStore); ok && *authorKey != "" {
+		if err := keys.PutAuthorKey(ctx, *authorKey, "author"); err != nil {
+			logs.Error(ctx, "Failed to add author key: %v", err)
+		}
+	}
+	// Saved decks are only seeded the first time, so restarting never touches the decks already there
+	if _, ok := p.(*filestore.LocalFilePlatform); (ok || p.DataStore().Summary() == "TestDataStore") && p.DataStore().IsEmpty() {
 		test.SetupTestData(ctx, p.DataStore(), logs)
 	}
+	if *cacheTTL > 0 {
+		p = platform.NewCachingPlatform(p, *cacheTTL, *cacheSize)
+		logs.Info(ctx, "Using %s", p.DataStore().Summary())
+	}
 
-	router := handlers.ApplicationRouter(p)
+	router := handlers.NewApplication(p).Router()
 
 	addr := p.ListenAddress()
 	logs.Info(ctx, "Server listening on port %s", addr)
@@ -43,8 +69,22 @@ func logEnvironment(logs platform.Logger, ctx context.Context) {
 	}
 }
 
+// envDuration reads a duration such as "30s" from an environment variable, if it is set and valid
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(os.Getenv(name)); err == nil {
+		return duration
+	}
+	return fallback
+}
+
 func getPlatform(ctx context.Context) platform.Platform {
-	if gcp.RunningOnGCloud() {
+	if *postgresURL != "" {
+		return pgstore.PostgresPlatform(ctx, *postgresURL)
+	} else if *sqlitePath != "" {
+		return sqlstore.SqlitePlatform(ctx, *sqlitePath)
+	} else if *dataDir != "" {
+		return filestore.FilePlatform(ctx, *dataDir)
+	} else if gcp.RunningOnGCloud() {
 		return gcp.GcpPlatform(ctx)
 	} else {
 		return platform.LocalPlatform(ctx)
@@ -0,0 +1,301 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+	"flashcards/internal/schema"
+)
+
+// Backups are zip archives of JSON documents, one per deck, with a manifest that restores check
+// before loading anything
+const ARCHIVE_FORMAT = "flashcards-backup"
+const ARCHIVE_VERSION = 1
+
+// The documents in an archive
+const MANIFEST_FILE = "manifest.json"
+const KEYS_FILE = "keys.json"
+const DECK_DIR = "decks"
+
+// Manifest describes an archive, and is the first document in it
+type Manifest struct {
+	Format     string
+	Version    int
+	Created    time.Time
+	Source     string // the data store that was backed up
+	Decks      int
+	AuthorKeys int
+}
+
+// archivedDeck is a deck document in an archive, with the schema version it was written at so
+// that decks from older backups are upgraded as they are restored
+type archivedDeck struct {
+	cards.Deck
+	SchemaVersion int
+}
+
+// What a restore does with decks and keys that the store already has
+const SKIP_EXISTING = "skip"
+const OVERWRITE_EXISTING = "overwrite"
+
+// Summary counts what a backup or restore did
+type Summary struct {
+	Decks       int
+	Cards       int
+	AuthorKeys  int
+	Skipped     int // decks and keys already in the store that were left alone
+	Overwritten int
+}
+
+func (summary Summary) String() string {
+	return fmt.Sprintf("%d decks with %d cards, %d author keys, %d skipped, %d overwritten",
+		summary.Decks, summary.Cards, summary.AuthorKeys, summary.Skipped, summary.Overwritten)
+}
+
+// Backup writes every deck in the store, and its author keys if it has them, to a zip archive.
+// Decks are found by walking down from the top-level decks, so nothing in the trash is included,
+// neither deleted decks nor deleted cards.
+func Backup(ctx context.Context, store platform.DataStore, out io.Writer) (Summary, error) {
+	var summary Summary
+
+	decks, err := allDecks(ctx, store)
+	if err != nil {
+		return summary, err
+	}
+	keys := make(map[string]string)
+	if keyStore, ok := store.(platform.AuthorKeyStore); ok {
+		if keys, err = keyStore.GetAuthorKeys(ctx); err != nil {
+			return summary, err
+		}
+	}
+
+	archive := zip.NewWriter(out)
+	manifest := Manifest{
+		Format:     ARCHIVE_FORMAT,
+		Version:    ARCHIVE_VERSION,
+		Created:    time.Now().UTC(),
+		Source:     store.Summary(),
+		Decks:      len(decks),
+		AuthorKeys: len(keys),
+	}
+	if err := writeDocument(archive, MANIFEST_FILE, manifest); err != nil {
+		return summary, err
+	}
+	for _, deck := range decks {
+		if err := writeDocument(archive, deckFile(deck.ID), archivedDeck{deck, schema.CurrentVersion()}); err != nil {
+			return summary, err
+		}
+		summary.Decks++
+		summary.Cards += len(deck.Cards)
+	}
+	if err := writeDocument(archive, KEYS_FILE, keys); err != nil {
+		return summary, err
+	}
+	summary.AuthorKeys = len(keys)
+
+	return summary, archive.Close()
+}
+
+// allDecks reads every deck reachable from the top level, parents before their children
+func allDecks(ctx context.Context, store platform.DataStore) ([]cards.Deck, error) {
+	decks := make([]cards.Deck, 0)
+	seen := make(map[string]bool)
+	queue := []string{""}
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		children, err := store.GetChildDecks(ctx, parentID)
+		if err != nil {
+			return decks, err
+		}
+		for _, child := range children {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			decks = append(decks, child)
+			queue = append(queue, child.ID)
+		}
+	}
+	return decks, nil
+}
+
+func deckFile(id string) string {
+	return path.Join(DECK_DIR, url.PathEscape(id)+".json")
+}
+
+func writeDocument(archive *zip.Writer, name string, document any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func readDocument(file *zip.File, into any) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(into); err != nil {
+		return fmt.Errorf("%s: %v", file.Name, err)
+	}
+	return nil
+}
+
+// ReadManifest checks that an archive is one that can be restored, and returns its manifest
+func ReadManifest(archive *zip.Reader) (Manifest, error) {
+	var manifest Manifest
+	for _, file := range archive.File {
+		if file.Name == MANIFEST_FILE {
+			if err := readDocument(file, &manifest); err != nil {
+				return manifest, err
+			}
+			if manifest.Format != ARCHIVE_FORMAT {
+				return manifest, fmt.Errorf("not a flashcards backup, the format is %q", manifest.Format)
+			}
+			if manifest.Version < 1 || manifest.Version > ARCHIVE_VERSION {
+				return manifest, fmt.Errorf("backup is version %d, only versions up to %d can be restored", manifest.Version, ARCHIVE_VERSION)
+			}
+			return manifest, nil
+		}
+	}
+	return manifest, fmt.Errorf("backup has no %s", MANIFEST_FILE)
+}
+
+// Restore loads the decks and author keys in an archive into the store. Decks and keys that are
+// already in the store are left alone or replaced, depending on existing.
+func Restore(ctx context.Context, store platform.DataStore, archive *zip.Reader, existing string) (Summary, error) {
+	var summary Summary
+
+	if existing != SKIP_EXISTING && existing != OVERWRITE_EXISTING {
+		return summary, fmt.Errorf("existing decks must be %q or %q, not %q", SKIP_EXISTING, OVERWRITE_EXISTING, existing)
+	}
+	if _, err := ReadManifest(archive); err != nil {
+		return summary, err
+	}
+
+	files := make([]*zip.File, 0)
+	for _, file := range archive.File {
+		if strings.HasPrefix(file.Name, DECK_DIR+"/") {
+			files = append(files, file)
+		} else if file.Name == KEYS_FILE {
+			if err := restoreKeys(ctx, store, file, existing, &summary); err != nil {
+				return summary, err
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	for _, file := range files {
+		doc := make(schema.Document)
+		if err := readDocument(file, &doc); err != nil {
+			return summary, err
+		}
+		id, _ := doc["ID"].(string)
+		if _, err := schema.UpgradeDeck(id, doc); err != nil {
+			return summary, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		var deck cards.Deck
+		if err := doc.To(&deck); err != nil {
+			return summary, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		if err := restoreDeck(ctx, store, deck, existing, &summary); err != nil {
+			return summary, fmt.Errorf("deck %s: %w", deck.ID, err)
+		}
+	}
+	return summary, nil
+}
+
+func restoreDeck(ctx context.Context, store platform.DataStore, deck cards.Deck, existing string, summary *Summary) error {
+	current, err := store.GetDeck(ctx, deck.ID)
+	if err == nil && existing == SKIP_EXISTING {
+		summary.Skipped++
+		return nil
+	} else if err != nil && !errors.Is(err, platform.ErrNotFound) {
+		return err
+	}
+
+	if err == nil {
+		// Replacing the deck means carrying on from its current version, and removing cards the backup does not have
+		deck.Version = current.Version
+		for cardID := range current.Cards {
+			if _, ok := deck.Cards[cardID]; !ok {
+				if err := store.DeleteCard(ctx, deck.ID, cardID); err != nil {
+					return err
+				}
+			}
+		}
+		for cardID, card := range deck.Cards {
+			card.Version = current.Cards[cardID].Version
+			deck.Cards[cardID] = card
+		}
+		summary.Overwritten++
+	}
+
+	// The deck's slug is claimed again, unless a different deck in this store already has it
+	if deck.Slug != "" {
+		err := store.PutSlug(ctx, deck.Slug, deck.ID)
+		if errors.Is(err, platform.ErrConflict) {
+			deck.Slug = ""
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if err := store.PutDeck(ctx, deck.ID, deck); err != nil {
+		return err
+	}
+	summary.Decks++
+	summary.Cards += len(deck.Cards)
+	return nil
+}
+
+func restoreKeys(ctx context.Context, store platform.DataStore, file *zip.File, existing string, summary *Summary) error {
+	keys := make(map[string]string)
+	if err := readDocument(file, &keys); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	keyStore, ok := store.(platform.AuthorKeyStore)
+	if !ok {
+		return fmt.Errorf("%s does not keep author keys, so the %d in the backup cannot be restored", store.Summary(), len(keys))
+	}
+
+	current, err := keyStore.GetAuthorKeys(ctx)
+	if err != nil {
+		return err
+	}
+	for key, role := range keys {
+		if currentRole, ok := current[key]; ok {
+			if existing == SKIP_EXISTING || currentRole == role {
+				summary.Skipped++
+				continue
+			}
+			summary.Overwritten++
+		}
+		if err := keyStore.PutAuthorKey(ctx, key, role); err != nil {
+			return err
+		}
+		summary.AuthorKeys++
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+func testStore() *platform.TestDataStore {
+	store := &platform.TestDataStore{}
+	store.Init(context.Background())
+	return store
+}
+
+func backupStore(t *testing.T, store platform.DataStore) *zip.Reader {
+	var buf bytes.Buffer
+	if _, err := Backup(context.Background(), store, &buf); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Backup is not a zip archive: %v", err)
+	}
+	return archive
+}
+
+func sourceStore() *platform.TestDataStore {
+	ctx := context.Background()
+	store := testStore()
+	parent := cards.Deck{ID: "P", Title: "Languages"}
+	parent.AddCard(cards.Card{ID: "P1", Question: "Hello"})
+	store.PutDeck(ctx, "P", parent)
+	child := cards.Deck{ID: "C", Title: "French", ParentID: "P", Reversible: true}
+	child.AddCard(cards.Card{ID: "C1", Question: "Bonjour", Tags: []string{"greetings"}})
+	child.AddCard(cards.Card{ID: "C2", Question: "Merci"})
+	store.PutDeck(ctx, "C", child)
+	store.PutAuthorKey(ctx, "key1", "author")
+	return store
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	archive := backupStore(t, sourceStore())
+
+	manifest, err := ReadManifest(archive)
+	if err != nil || manifest.Version != ARCHIVE_VERSION || manifest.Decks != 2 || manifest.AuthorKeys != 1 {
+		t.Errorf("Unexpected manifest %+v, %v", manifest, err)
+	}
+
+	target := testStore()
+	summary, err := Restore(ctx, target, archive, SKIP_EXISTING)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if summary.Decks != 2 || summary.Cards != 3 || summary.AuthorKeys != 1 || summary.Skipped != 0 {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+
+	child, err := target.GetDeck(ctx, "C")
+	if err != nil || child.Title != "French" || child.ParentID != "P" || !child.Reversible || len(child.Cards) != 2 {
+		t.Errorf("Unexpected restored deck %+v, %v", child, err)
+	}
+	if card := child.Cards["C1"]; card.Question != "Bonjour" || len(card.Tags) != 1 {
+		t.Errorf("Unexpected restored card %+v", card)
+	}
+	if !target.IsValidAuthor("key1") {
+		t.Error("Author key not restored")
+	}
+}
+
+func TestRestoreExisting(t *testing.T) {
+	ctx := context.Background()
+	archive := backupStore(t, sourceStore())
+
+	target := testStore()
+	changed := cards.Deck{ID: "C", Title: "Changed"}
+	changed.AddCard(cards.Card{ID: "C1", Question: "Changed"})
+	changed.AddCard(cards.Card{ID: "EXTRA", Question: "Not in the backup"})
+	target.PutDeck(ctx, "C", changed)
+	target.PutAuthorKey(ctx, "key1", "learner")
+
+	summary, err := Restore(ctx, target, archive, SKIP_EXISTING)
+	if err != nil || summary.Decks != 1 || summary.Skipped != 2 {
+		t.Errorf("Unexpected summary %v, %v", summary, err)
+	}
+	if deck, _ := target.GetDeck(ctx, "C"); deck.Title != "Changed" {
+		t.Errorf("Existing deck replaced when skipping: %s", deck.Title)
+	}
+
+	summary, err = Restore(ctx, target, archive, OVERWRITE_EXISTING)
+	if err != nil || summary.Overwritten != 3 {
+		t.Errorf("Unexpected summary %v, %v", summary, err)
+	}
+	deck, _ := target.GetDeck(ctx, "C")
+	if deck.Title != "French" || len(deck.Cards) != 2 || deck.Cards["C1"].Question != "Bonjour" {
+		t.Errorf("Existing deck not replaced when overwriting: %+v", deck)
+	}
+	if !target.IsValidAuthor("key1") {
+		t.Error("Author key role not replaced when overwriting")
+	}
+}
+
+func TestRestoreChecksManifest(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	w, _ := archive.Create(MANIFEST_FILE)
+	json.NewEncoder(w).Encode(Manifest{Format: ARCHIVE_FORMAT, Version: ARCHIVE_VERSION + 1})
+	archive.Close()
+
+	reader, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	_, err := Restore(context.Background(), testStore(), reader, SKIP_EXISTING)
+	if err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("Expected version error, got %v", err)
+	}
+
+	_, err = Restore(context.Background(), testStore(), backupStore(t, sourceStore()), "merge")
+	if err == nil {
+		t.Error("Expected error for unknown existing option")
+	}
+}
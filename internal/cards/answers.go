@@ -0,0 +1,135 @@
+package cards
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Typed answers are accepted if no more than this fraction of their characters are wrong
+const ANSWER_TOLERANCE = 0.2
+
+// AnswerCheck is the result of comparing a typed answer with a card's acceptable answers.
+type AnswerCheck struct {
+	Given    string
+	Expected string
+	Correct  bool
+	Distance int
+	Diff     []DiffPart
+}
+
+// DiffPart is a run of text that is the same in both answers, or only in the expected ("added") or given ("removed") answer.
+type DiffPart struct {
+	Kind string
+	Text string
+}
+
+// AcceptableAnswers returns the card's answer along with any alternatives the author has listed.
+func (card Card) AcceptableAnswers() []string {
+	answers := []string{card.Answer}
+	for _, alt := range card.Alternatives {
+		if strings.TrimSpace(alt) != "" {
+			answers = append(answers, alt)
+		}
+	}
+	return answers
+}
+
+// NormaliseAnswer lower-cases the text and strips accents and punctuation, leaving single spaces between words.
+func NormaliseAnswer(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// drop combining accents
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// EditDistance returns the Levenshtein distance between two strings.
+func EditDistance(a string, b string) int {
+	return editMatrix([]rune(a), []rune(b))[len([]rune(a))][len([]rune(b))]
+}
+
+func editMatrix(a []rune, b []rune) [][]int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+		}
+	}
+	return d
+}
+
+// CheckAnswer compares a typed answer against each acceptable answer, reporting on the closest one.
+func CheckAnswer(acceptable []string, given string) AnswerCheck {
+	check := AnswerCheck{Given: given, Distance: -1}
+	normalGiven := NormaliseAnswer(given)
+
+	for _, answer := range acceptable {
+		normalAnswer := NormaliseAnswer(answer)
+		distance := EditDistance(normalGiven, normalAnswer)
+		if check.Distance < 0 || distance < check.Distance {
+			check.Expected = answer
+			check.Distance = distance
+			check.Correct = float64(distance) <= ANSWER_TOLERANCE*float64(len([]rune(normalAnswer)))
+			check.Diff = Diff(normalGiven, normalAnswer)
+		}
+	}
+
+	return check
+}
+
+// Diff lists the changes needed to turn the given text into the expected text, character by character.
+func Diff(given string, expected string) []DiffPart {
+	a := []rune(given)
+	b := []rune(expected)
+	d := editMatrix(a, b)
+
+	parts := make([]DiffPart, 0)
+	add := func(kind string, r rune) {
+		if len(parts) > 0 && parts[0].Kind == kind {
+			parts[0].Text = string(r) + parts[0].Text
+		} else {
+			parts = append([]DiffPart{{Kind: kind, Text: string(r)}}, parts...)
+		}
+	}
+
+	i, j := len(a), len(b)
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && a[i-1] == b[j-1] && d[i][j] == d[i-1][j-1]:
+			add("same", a[i-1])
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+1:
+			add("added", b[j-1])
+			add("removed", a[i-1])
+			i, j = i-1, j-1
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			add("removed", a[i-1])
+			i--
+		default:
+			add("added", b[j-1])
+			j--
+		}
+	}
+
+	return parts
+}
@@ -0,0 +1,64 @@
+package cards
+
+import "testing"
+
+func TestNormaliseAnswer(t *testing.T) {
+	normalised := NormaliseAnswer("  Crème   Brûlée, s'il vous plaît! ")
+	if normalised != "creme brulee s il vous plait" {
+		t.Errorf("Unexpected normalised answer: %s", normalised)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	if EditDistance("kitten", "sitting") != 3 {
+		t.Errorf("Unexpected edit distance: %d", EditDistance("kitten", "sitting"))
+	}
+	if EditDistance("", "abc") != 3 {
+		t.Errorf("Unexpected edit distance from empty string")
+	}
+}
+
+func TestCheckAnswerWithTypo(t *testing.T) {
+	card := Card{Answer: "Much wood would be chucked"}
+
+	check := CheckAnswer(card.AcceptableAnswers(), "much wood woud be chucked")
+	if !check.Correct {
+		t.Errorf("Answer with a small typo was not accepted: %+v", check)
+	}
+
+	check = CheckAnswer(card.AcceptableAnswers(), "no wood at all")
+	if check.Correct {
+		t.Errorf("Wrong answer was accepted: %+v", check)
+	}
+}
+
+func TestCheckAnswerAlternatives(t *testing.T) {
+	card := Card{Answer: "42", Alternatives: []string{"forty two", " "}}
+
+	check := CheckAnswer(card.AcceptableAnswers(), "Forty-two")
+	if !check.Correct || check.Expected != "forty two" {
+		t.Errorf("Alternative answer was not accepted: %+v", check)
+	}
+	if len(card.AcceptableAnswers()) != 2 {
+		t.Errorf("Blank alternative was not ignored: %v", card.AcceptableAnswers())
+	}
+
+	check = CheckAnswer(card.AcceptableAnswers(), "43")
+	if check.Correct {
+		t.Errorf("Short answer with a wrong digit was accepted")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	parts := Diff("cat", "cart")
+
+	expected := []DiffPart{{"same", "ca"}, {"added", "r"}, {"same", "t"}}
+	if len(parts) != len(expected) {
+		t.Fatalf("Unexpected diff: %v", parts)
+	}
+	for i := range parts {
+		if parts[i] != expected[i] {
+			t.Errorf("Unexpected diff part %d: %v", i, parts[i])
+		}
+	}
+}
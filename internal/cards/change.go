@@ -0,0 +1,155 @@
+package cards
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The kinds of change kept in a deck's history
+const CHANGE_CREATED = "created"
+const CHANGE_EDITED = "edited"
+const CHANGE_DELETED = "deleted"
+const CHANGE_RESTORED = "restored"
+const CHANGE_REVERTED = "reverted"
+
+// Change is an entry in a deck's history, recording a card or the deck's own settings as they were
+// before and after being created, edited or deleted.
+type Change struct {
+	ID         string
+	DeckID     string
+	CardID     string // empty for changes to the deck's own settings
+	Action     string
+	Time       time.Time
+	Actor      string // who made the change, by their user name
+	CardBefore *Card  // nil when the card was created
+	CardAfter  *Card  // nil when the card was deleted
+	DeckBefore *Deck  // the deck's settings, without its cards
+	DeckAfter  *Deck
+}
+
+// FieldChange is a field that differs between the before and after values of a change
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// ChangeID makes IDs that sort in the order the changes were made
+func ChangeID(now time.Time) string {
+	return fmt.Sprintf("%016X-%s", now.UnixNano(), RandomCardId())
+}
+
+// CardChange records a card being created (before is nil), edited or deleted (after is nil).
+func CardChange(action string, before *Card, after *Card, actor string, now time.Time) Change {
+	change := Change{
+		ID:         ChangeID(now),
+		Action:     action,
+		Time:       now,
+		Actor:      actor,
+		CardBefore: before,
+		CardAfter:  after,
+	}
+	for _, card := range []*Card{before, after} {
+		if card != nil {
+			change.DeckID, change.CardID = card.DeckID, card.ID
+		}
+	}
+	return change
+}
+
+// DeckChange records a deck's own settings being created, edited or deleted. Its cards are left
+// out, as changes to them are recorded one card at a time.
+func DeckChange(action string, before *Deck, after *Deck, actor string, now time.Time) Change {
+	change := Change{
+		ID:     ChangeID(now),
+		Action: action,
+		Time:   now,
+		Actor:  actor,
+	}
+	if before != nil {
+		settings := *before
+		settings.Cards = nil
+		change.DeckBefore, change.DeckID = &settings, settings.ID
+	}
+	if after != nil {
+		settings := *after
+		settings.Cards = nil
+		change.DeckAfter, change.DeckID = &settings, settings.ID
+	}
+	return change
+}
+
+func (change Change) IsDeck() bool {
+	return change.CardID == ""
+}
+
+// Title describes what was changed in the history listing
+func (change Change) Title() string {
+	for _, card := range []*Card{change.CardAfter, change.CardBefore} {
+		if card != nil {
+			return card.Question
+		}
+	}
+	for _, deck := range []*Deck{change.DeckAfter, change.DeckBefore} {
+		if deck != nil {
+			return deck.Title
+		}
+	}
+	return change.ID
+}
+
+// CanRevert reports whether the card can be put back the way it was after this change
+func (change Change) CanRevert() bool {
+	return change.CardAfter != nil
+}
+
+// Differences lists the fields whose values differ before and after the change, in the order they
+// appear on the edit forms. Fields of a created or deleted card or deck are compared with blanks.
+func (change Change) Differences() []FieldChange {
+	var before, after [][2]string
+	if change.IsDeck() {
+		before, after = deckFields(change.DeckBefore), deckFields(change.DeckAfter)
+	} else {
+		before, after = cardFields(change.CardBefore), cardFields(change.CardAfter)
+	}
+	differences := make([]FieldChange, 0)
+	for i := range before {
+		if before[i][1] != after[i][1] {
+			differences = append(differences, FieldChange{Field: before[i][0], Before: before[i][1], After: after[i][1]})
+		}
+	}
+	return differences
+}
+
+func cardFields(card *Card) [][2]string {
+	if card == nil {
+		card = &Card{}
+	}
+	reversible := ""
+	if card.Reversible != nil {
+		reversible = fmt.Sprint(*card.Reversible)
+	}
+	return [][2]string{
+		{"Type", card.Type},
+		{"Question", card.Question},
+		{"Answer", card.Answer},
+		{"Hint", card.Hint},
+		{"Alternatives", strings.Join(card.Alternatives, "\n")},
+		{"Choices", strings.Join(card.Choices, "\n")},
+		{"Reversible", reversible},
+		{"Tags", card.TagList()},
+	}
+}
+
+func deckFields(deck *Deck) [][2]string {
+	if deck == nil {
+		return [][2]string{{"Title", ""}, {"Reversible", ""}, {"Parent", ""}, {"Slug", ""}}
+	}
+	return [][2]string{
+		{"Title", deck.Title},
+		{"Reversible", fmt.Sprint(deck.Reversible)},
+		{"Parent", deck.ParentID},
+		{"Slug", deck.Slug},
+	}
+}
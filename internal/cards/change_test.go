@@ -0,0 +1,52 @@
+package cards
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCardChange(t *testing.T) {
+	now := time.Now()
+	before := Card{ID: "C1", DeckID: "D1", Question: "Q", Answer: "A", Tags: []string{"t"}}
+	after := before
+	after.Answer = "B"
+	after.Tags = []string{"t", "u"}
+
+	change := CardChange(CHANGE_EDITED, &before, &after, "learner 1", now)
+	if change.DeckID != "D1" || change.CardID != "C1" || change.IsDeck() || change.Title() != "Q" || !change.CanRevert() {
+		t.Errorf("Unexpected change %+v", change)
+	}
+	expected := []FieldChange{{"Answer", "A", "B"}, {"Tags", "t", "t, u"}}
+	if differences := change.Differences(); !reflect.DeepEqual(differences, expected) {
+		t.Errorf("Unexpected differences %+v", differences)
+	}
+
+	deleted := CardChange(CHANGE_DELETED, &before, nil, "learner 1", now)
+	if deleted.CardID != "C1" || deleted.CanRevert() || len(deleted.Differences()) != 3 {
+		t.Errorf("Unexpected deletion %+v, %+v", deleted, deleted.Differences())
+	}
+}
+
+func TestDeckChange(t *testing.T) {
+	deck := Deck{ID: "D1", Title: "Deck"}
+	deck.AddCard(Card{ID: "C1"})
+
+	change := DeckChange(CHANGE_CREATED, nil, &deck, "key 1", time.Now())
+	if change.DeckID != "D1" || !change.IsDeck() || change.DeckAfter.Cards != nil || change.Title() != "Deck" {
+		t.Errorf("Unexpected change %+v", change)
+	}
+	if differences := change.Differences(); len(differences) != 2 || differences[0].After != "Deck" {
+		t.Errorf("Unexpected differences %+v", differences)
+	}
+	if len(deck.Cards) != 1 {
+		t.Error("Recording a change removed the deck's cards")
+	}
+}
+
+func TestChangeIDsSort(t *testing.T) {
+	now := time.Now()
+	if first, second := ChangeID(now), ChangeID(now.Add(time.Millisecond)); first >= second {
+		t.Errorf("Change IDs out of order: %s, %s", first, second)
+	}
+}
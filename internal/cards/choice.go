@@ -0,0 +1,60 @@
+package cards
+
+import (
+	"math/rand"
+	"strings"
+)
+
+const CHOICE_CARD = "choice"
+
+// The number of options offered for a multiple-choice card, including the correct answer
+const CHOICE_COUNT = 4
+
+func (card Card) IsChoice() bool {
+	return card.Type == CHOICE_CARD
+}
+
+// Choices returns the options for a multiple-choice card in a random order. The wrong options are the
+// card's own Choices if the author gave any, otherwise they are sampled from other cards' answers.
+func (deck *Deck) Choices(card Card) []string {
+	options := []string{card.Answer}
+	seen := map[string]bool{strings.TrimSpace(card.Answer): true}
+
+	add := func(option string) {
+		trimmed := strings.TrimSpace(option)
+		if trimmed == "" || seen[trimmed] || len(options) >= CHOICE_COUNT {
+			return
+		}
+		seen[trimmed] = true
+		options = append(options, option)
+	}
+
+	if len(card.Choices) > 0 {
+		for _, choice := range card.Choices {
+			add(choice)
+		}
+	} else {
+		others := make([]string, 0, len(deck.Cards))
+		for id, other := range deck.Cards {
+			if id != card.ID && !other.IsCloze() {
+				others = append(others, other.Answer)
+			}
+		}
+		rand.Shuffle(len(others), func(i, j int) {
+			others[i], others[j] = others[j], others[i]
+		})
+		for _, answer := range others {
+			add(answer)
+		}
+	}
+
+	rand.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	return options
+}
+
+// IsCorrectChoice checks whether the option picked by the learner is the card's answer.
+func (card Card) IsCorrectChoice(choice string) bool {
+	return strings.TrimSpace(choice) == strings.TrimSpace(card.Answer)
+}
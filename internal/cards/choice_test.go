@@ -0,0 +1,52 @@
+package cards
+
+import "testing"
+
+func TestChoicesFromDeck(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1", Type: CHOICE_CARD, Answer: "A"})
+	deck.PutCard("2", Card{ID: "2", Answer: "B"})
+	deck.PutCard("3", Card{ID: "3", Answer: "C"})
+	deck.PutCard("4", Card{ID: "4", Answer: "C"})
+	deck.PutCard("5", Card{ID: "5", Answer: ""})
+
+	choices := deck.Choices(deck.GetCard("1"))
+
+	if len(choices) != 3 {
+		t.Errorf("Unexpected choices: %v", choices)
+	}
+	found := false
+	for _, choice := range choices {
+		if choice == "A" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Correct answer missing from choices: %v", choices)
+	}
+}
+
+func TestAuthoredChoices(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	card := Card{ID: "1", Type: CHOICE_CARD, Answer: "A", Choices: []string{"X", "Y", "Z", "W"}}
+	deck.PutCard("1", card)
+	deck.PutCard("2", Card{ID: "2", Answer: "B"})
+
+	choices := deck.Choices(card)
+
+	if len(choices) != CHOICE_COUNT {
+		t.Errorf("Unexpected number of choices: %v", choices)
+	}
+	for _, choice := range choices {
+		if choice == "B" {
+			t.Errorf("Deck answer used despite authored choices: %v", choices)
+		}
+	}
+}
+
+func TestIsCorrectChoice(t *testing.T) {
+	card := Card{Answer: "42"}
+	if !card.IsCorrectChoice(" 42") || card.IsCorrectChoice("43") {
+		t.Errorf("Unexpected choice check")
+	}
+}
@@ -0,0 +1,75 @@
+package cards
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const BASIC_CARD = ""
+const CLOZE_CARD = "cloze"
+
+// Matches {{c1::term}} and {{c1::term::hint}}
+var clozePattern = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+func (card Card) IsCloze() bool {
+	return card.Type == CLOZE_CARD
+}
+
+// ClozeNumbers returns the distinct deletion numbers used in the text, in ascending order.
+func ClozeNumbers(text string) []int {
+	found := make(map[int]bool)
+	for _, match := range clozePattern.FindAllStringSubmatch(text, -1) {
+		found[clozeNumber(match)] = true
+	}
+	numbers := make([]int, 0, len(found))
+	for n := range found {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// ClozeQuestion blanks out deletion n, showing its hint if there is one, and shows every other deletion as plain text.
+func ClozeQuestion(text string, n int) string {
+	return clozeReplace(text, n, func(term string, hint string) string {
+		if hint != "" {
+			return "[" + hint + "]"
+		}
+		return "[...]"
+	})
+}
+
+// ClozeAnswer fills in deletion n in bold, and shows every other deletion as plain text.
+func ClozeAnswer(text string, n int) string {
+	return clozeReplace(text, n, func(term string, hint string) string {
+		return "**" + term + "**"
+	})
+}
+
+// ClozeTerms returns the terms deleted by deletion n.
+func ClozeTerms(text string, n int) []string {
+	terms := make([]string, 0)
+	for _, match := range clozePattern.FindAllStringSubmatch(text, -1) {
+		if clozeNumber(match) == n {
+			terms = append(terms, match[2])
+		}
+	}
+	return terms
+}
+
+// clozeNumber is the deletion number of a match, compared as a number so that c01 is the same as c1
+func clozeNumber(match []string) int {
+	n, _ := strconv.Atoi(match[1])
+	return n
+}
+
+func clozeReplace(text string, n int, deleted func(term string, hint string) string) string {
+	return clozePattern.ReplaceAllStringFunc(text, func(marker string) string {
+		match := clozePattern.FindStringSubmatch(marker)
+		if clozeNumber(match) == n {
+			return deleted(match[2], match[3])
+		}
+		return match[2]
+	})
+}
@@ -0,0 +1,67 @@
+package cards
+
+import "testing"
+
+const clozeText = "The {{c1::mitochondria}} is the {{c2::powerhouse::job}} of the {{c1::cell}}"
+
+func TestClozeNumbers(t *testing.T) {
+	numbers := ClozeNumbers(clozeText)
+	if len(numbers) != 2 || numbers[0] != 1 || numbers[1] != 2 {
+		t.Errorf("Unexpected cloze numbers: %v", numbers)
+	}
+}
+
+func TestClozeQuestionAndAnswer(t *testing.T) {
+	question := ClozeQuestion(clozeText, 1)
+	if question != "The [...] is the powerhouse of the [...]" {
+		t.Errorf("Unexpected cloze question: %s", question)
+	}
+
+	question = ClozeQuestion(clozeText, 2)
+	if question != "The mitochondria is the [job] of the cell" {
+		t.Errorf("Unexpected cloze question with hint: %s", question)
+	}
+
+	answer := ClozeAnswer(clozeText, 2)
+	if answer != "The mitochondria is the **powerhouse** of the cell" {
+		t.Errorf("Unexpected cloze answer: %s", answer)
+	}
+}
+
+func TestClozeLeadingZeros(t *testing.T) {
+	text := "The {{c01::cell}} has a {{c1::nucleus}}"
+	if numbers := ClozeNumbers(text); len(numbers) != 1 || numbers[0] != 1 {
+		t.Errorf("Unexpected cloze numbers: %v", numbers)
+	}
+	if question := ClozeQuestion(text, 1); question != "The [...] has a [...]" {
+		t.Errorf("Unexpected cloze question: %s", question)
+	}
+	if terms := ClozeTerms(text, 1); len(terms) != 2 || terms[0] != "cell" {
+		t.Errorf("Unexpected cloze terms: %v", terms)
+	}
+}
+
+func TestClozePrompts(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("A", Card{ID: "A", Type: CLOZE_CARD, Question: clozeText})
+	deck.PutCard("B", Card{ID: "B", Question: "Basic"})
+
+	prompts := deck.Prompts()
+	if len(prompts) != 3 {
+		t.Fatalf("Unexpected number of prompts: %d", len(prompts))
+	}
+
+	prompt := deck.GetPrompt("A.c2")
+	if prompt.Card.ID != "A" || prompt.Cloze != 2 {
+		t.Errorf("Unexpected prompt for key: %+v", prompt)
+	}
+	if prompt.AcceptableAnswers()[0] != "powerhouse" {
+		t.Errorf("Unexpected cloze answers: %v", prompt.AcceptableAnswers())
+	}
+	if deck.GetPrompt("A").Key() != "A.c1" {
+		t.Errorf("Cloze card did not default to first deletion: %s", deck.GetPrompt("A").Key())
+	}
+	if deck.GetPrompt("B").Key() != "B" {
+		t.Errorf("Unexpected key for basic prompt: %s", deck.GetPrompt("B").Key())
+	}
+}
@@ -0,0 +1,58 @@
+package cards
+
+import (
+	"errors"
+	"math/rand"
+	"regexp"
+	"strings"
+)
+
+// DECK_CODE_ALPHABET is what deck codes are made of. It leaves out 0, 1, I, L and O, which are
+// easily mistaken for each other when a code is read aloud or copied from a board, and U, which
+// sounds like "you" and looks like V.
+const DECK_CODE_ALPHABET = "23456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const MIN_SLUG_LENGTH = 3
+const MAX_SLUG_LENGTH = 40
+
+// Deck codes are two groups of four, older ones being hexadecimal
+var deckCodePattern = regexp.MustCompile(`^[0-9A-Z]{4}-[0-9A-Z]{4}$`)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// RandomDeckId makes a new deck code, which may already be in use.
+func RandomDeckId() string {
+	code := make([]byte, 0, 9)
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		code = append(code, DECK_CODE_ALPHABET[rand.Intn(len(DECK_CODE_ALPHABET))])
+	}
+	return string(code)
+}
+
+// IsDeckCode reports whether the text has the shape of a deck code, whichever letters it uses.
+func IsDeckCode(text string) bool {
+	return deckCodePattern.MatchString(strings.ToUpper(text))
+}
+
+// NormaliseSlug tidies up a slug as typed, ready for CheckSlug.
+func NormaliseSlug(slug string) string {
+	return strings.ToLower(strings.TrimSpace(slug))
+}
+
+// CheckSlug explains why a normalised slug cannot be claimed for a deck, or returns nil if it can.
+func CheckSlug(slug string) error {
+	if len(slug) < MIN_SLUG_LENGTH || len(slug) > MAX_SLUG_LENGTH {
+		return errors.New("slugs must be between 3 and 40 characters long")
+	}
+	if !slugPattern.MatchString(slug) {
+		return errors.New("slugs can only have lowercase letters and numbers, with single hyphens between words")
+	}
+	// Otherwise the slug could hide a deck with that code
+	if IsDeckCode(slug) {
+		return errors.New("slugs cannot look like deck codes")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cards
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomDeckIdAlphabet(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		id := RandomDeckId()
+		if !IsDeckCode(id) || id[4] != '-' {
+			t.Fatalf("Unexpected deck code %s", id)
+		}
+		for _, c := range strings.ReplaceAll(id, "-", "") {
+			if !strings.ContainsRune(DECK_CODE_ALPHABET, c) {
+				t.Fatalf("Deck code %s uses %c, which is not in the alphabet", id, c)
+			}
+		}
+	}
+	for _, c := range "01ILOU" {
+		if strings.ContainsRune(DECK_CODE_ALPHABET, c) {
+			t.Errorf("Alphabet includes ambiguous %c", c)
+		}
+	}
+}
+
+func TestIsDeckCode(t *testing.T) {
+	for _, code := range []string{"ABCD-2345", "1F2E-00AB", "abcd-2345"} {
+		if !IsDeckCode(code) {
+			t.Errorf("%s not recognised as a deck code", code)
+		}
+	}
+	for _, text := range []string{"ABCD2345", "ABC-12345", "french-verbs", ""} {
+		if IsDeckCode(text) {
+			t.Errorf("%s recognised as a deck code", text)
+		}
+	}
+}
+
+func TestCheckSlug(t *testing.T) {
+	if slug := NormaliseSlug("  French-Verbs "); slug != "french-verbs" {
+		t.Errorf("Unexpected normalised slug %s", slug)
+	}
+	for _, slug := range []string{"french-verbs", "year-7-maths", "abc"} {
+		if err := CheckSlug(slug); err != nil {
+			t.Errorf("Slug %s refused: %v", slug, err)
+		}
+	}
+	for _, slug := range []string{"ab", strings.Repeat("a", 41), "French", "two--hyphens", "-start", "end-", "has space", "café", "abcd-2345"} {
+		if err := CheckSlug(slug); err == nil {
+			t.Errorf("Slug %q accepted", slug)
+		}
+	}
+}
@@ -3,24 +3,33 @@ package cards
 import (
 	"fmt"
 	"math/rand"
+	"time"
 )
 
 type Card struct {
-	ID       string
-	DeckID   string
-	Question string
-	Answer   string
-	Hint     string
+	ID           string
+	DeckID       string
+	Type         string
+	Question     string
+	Answer       string
+	Hint         string
+	Alternatives []string
+	Choices      []string // wrong options for multiple-choice cards
+	Reversible   *bool    // nil to use the deck default
+	Tags         []string
+	Version      int // the number of times the card has been written, for spotting conflicting edits
 }
 
 type Deck struct {
-	ID    string
-	Title string
-	Cards map[string]Card
-}
-
-func RandomDeckId() string {
-	return fmt.Sprintf("%04X-%04X", rand.Intn(0xFFFF), rand.Intn(0xFFFF))
+	ID         string
+	Title      string
+	Cards      map[string]Card
+	Reversible bool
+	ParentID   string
+	Version    int // the number of times the deck's own settings have been written, for spotting conflicting edits
+	Created    time.Time
+	Slug       string // the memorable name claimed for the deck, if any, which redirects to its code
+	CreatedBy  string // the name of the user who created the deck, empty for decks made before user accounts
 }
 
 func RandomCardId() string {
@@ -0,0 +1,76 @@
+package cards
+
+import (
+	"math/rand"
+	"time"
+)
+
+const LEITNER_BOXES = 5
+
+type BoxCount struct {
+	Box   int
+	Count int
+}
+
+// Box returns the Leitner box a prompt is in, with prompts that have never been answered starting in box 1.
+func (progress *Progress) Box(key string) int {
+	box := progress.Reviews[key].Box
+	if box < 1 {
+		return 1
+	}
+	return box
+}
+
+// Leitner moves a prompt up one box for a correct answer, or back to box 1 for a wrong one.
+// The prompt's SM-2 schedule is left alone, so it is still unseen as far as reviews go.
+func (progress *Progress) Leitner(key string, correct bool, now time.Time) {
+	review := progress.GetReview(key)
+	if correct {
+		review.Box = progress.Box(key) + 1
+		if review.Box > LEITNER_BOXES {
+			review.Box = LEITNER_BOXES
+		}
+	} else {
+		review.Box = 1
+	}
+	review.Reviewed = now
+	if progress.Reviews == nil {
+		progress.Reviews = make(map[string]Review)
+	}
+	progress.Reviews[key] = review
+}
+
+// LeitnerPrompt picks a prompt at random, with each box drawn from twice as often as the box above it.
+func (progress *Progress) LeitnerPrompt(prompts []Prompt) Prompt {
+	total := 0
+	for _, prompt := range prompts {
+		total += boxWeight(progress.Box(prompt.Key()))
+	}
+	if total == 0 {
+		return Prompt{Card: Card{ID: "ERROR"}}
+	}
+
+	counter := rand.Intn(total)
+	for _, prompt := range prompts {
+		counter -= boxWeight(progress.Box(prompt.Key()))
+		if counter < 0 {
+			return prompt
+		}
+	}
+	return Prompt{Card: Card{ID: "ERROR"}}
+}
+
+func boxWeight(box int) int {
+	return 1 << (LEITNER_BOXES - box)
+}
+
+func (progress *Progress) BoxCounts(prompts []Prompt) []BoxCount {
+	counts := make([]BoxCount, LEITNER_BOXES)
+	for i := range counts {
+		counts[i].Box = i + 1
+	}
+	for _, prompt := range prompts {
+		counts[progress.Box(prompt.Key())-1].Count++
+	}
+	return counts
+}
@@ -0,0 +1,92 @@
+package cards
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeitnerPromotion(t *testing.T) {
+	progress := Progress{}
+
+	if progress.Box("1") != 1 {
+		t.Errorf("Unseen card not in box 1: %d", progress.Box("1"))
+	}
+
+	for i := 0; i < LEITNER_BOXES+2; i++ {
+		progress.Leitner("1", true, time.Now())
+	}
+	if progress.Box("1") != LEITNER_BOXES {
+		t.Errorf("Card not in top box after repeated correct answers: %d", progress.Box("1"))
+	}
+
+	progress.Leitner("1", false, time.Now())
+	if progress.Box("1") != 1 {
+		t.Errorf("Card not back in box 1 after wrong answer: %d", progress.Box("1"))
+	}
+}
+
+func TestLeitnerLeavesReviewOrder(t *testing.T) {
+	now := time.Now()
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1"})
+	deck.PutCard("2", Card{ID: "2"})
+
+	progress := Progress{}
+	progress.Grade("1", Again, now.Add(-time.Hour))
+	progress.Leitner("2", true, now)
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "1" {
+		t.Errorf("Expected overdue card before one answered in Leitner mode, got %s", prompt.Key())
+	}
+
+	progress.Grade("1", Easy, now)
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "2" {
+		t.Errorf("Expected card answered in Leitner mode to count as unseen, got %s", prompt.Key())
+	}
+	if progress.Box("2") != 2 {
+		t.Errorf("Leitner box lost: %d", progress.Box("2"))
+	}
+}
+
+func TestLeitnerCardFavoursLowBoxes(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1"})
+	deck.PutCard("2", Card{ID: "2"})
+
+	progress := Progress{}
+	for i := 0; i < LEITNER_BOXES; i++ {
+		progress.Leitner("2", true, time.Now())
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[progress.LeitnerPrompt(deck.Prompts()).Key()]++
+	}
+
+	if counts["1"] <= counts["2"] {
+		t.Errorf("Box 1 card not drawn more often: %v", counts)
+	}
+	if counts["2"] == 0 {
+		t.Errorf("Top box card never drawn: %v", counts)
+	}
+}
+
+func TestBoxCounts(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1"})
+	deck.PutCard("2", Card{ID: "2"})
+	deck.PutCard("3", Card{ID: "3"})
+
+	progress := Progress{}
+	progress.Leitner("3", true, time.Now())
+
+	counts := progress.BoxCounts(deck.Prompts())
+
+	if len(counts) != LEITNER_BOXES {
+		t.Fatalf("Unexpected number of boxes: %d", len(counts))
+	}
+	if counts[0].Box != 1 || counts[0].Count != 2 || counts[1].Count != 1 {
+		t.Errorf("Unexpected box counts: %v", counts)
+	}
+}
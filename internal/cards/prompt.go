@@ -0,0 +1,169 @@
+package cards
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const FORWARD = "forward"
+const REVERSE = "reverse"
+
+// Prompt is a single reviewable item. Most cards have one prompt, but cloze
+// cards have one prompt for each deletion and reversible cards have a second
+// prompt that shows the answer and expects the question.
+type Prompt struct {
+	Card     Card
+	Cloze    int
+	Reversed bool
+}
+
+// Key identifies the prompt within a deck, and is what learner progress is recorded against.
+func (prompt Prompt) Key() string {
+	if prompt.Cloze > 0 {
+		return fmt.Sprintf("%s.c%d", prompt.Card.ID, prompt.Cloze)
+	}
+	if prompt.Reversed {
+		return prompt.Card.ID + ".r"
+	}
+	return prompt.Card.ID
+}
+
+// ParsePromptKey splits a prompt key into its card ID, cloze deletion number and direction.
+func ParsePromptKey(key string) (string, int, bool) {
+	cardID, suffix, found := strings.Cut(key, ".")
+	if !found {
+		return key, 0, false
+	}
+	if suffix == "r" {
+		return cardID, 0, true
+	}
+	var cloze int
+	fmt.Sscanf(suffix, "c%d", &cloze)
+	return cardID, cloze, false
+}
+
+// IsReversible reports whether the card can also be studied answer-to-question, using
+// the deck's default unless the card has its own setting.
+func (card Card) IsReversible(deckDefault bool) bool {
+	if card.IsCloze() || card.IsChoice() {
+		return false
+	}
+	if card.Reversible != nil {
+		return *card.Reversible
+	}
+	return deckDefault
+}
+
+// ReversibleSetting describes the card's own reversible setting for forms: "yes", "no" or "" to use the deck default.
+func (card Card) ReversibleSetting() string {
+	if card.Reversible == nil {
+		return ""
+	}
+	if *card.Reversible {
+		return "yes"
+	}
+	return "no"
+}
+
+// CardPrompts returns every prompt generated from the card.
+func (deck *Deck) CardPrompts(card Card) []Prompt {
+	if card.IsCloze() {
+		prompts := make([]Prompt, 0)
+		for _, n := range ClozeNumbers(card.Question) {
+			prompts = append(prompts, Prompt{Card: card, Cloze: n})
+		}
+		return prompts
+	}
+	prompts := []Prompt{{Card: card}}
+	if card.IsReversible(deck.Reversible) {
+		prompts = append(prompts, Prompt{Card: card, Reversed: true})
+	}
+	return prompts
+}
+
+// Prompts returns every prompt in the deck, ordered by question for display.
+func (deck *Deck) Prompts() []Prompt {
+	prompts := make([]Prompt, 0, len(deck.Cards))
+	for _, card := range deck.Cards {
+		prompts = append(prompts, deck.CardPrompts(card)...)
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		if prompts[i].Card.Question == prompts[j].Card.Question {
+			return prompts[i].Key() < prompts[j].Key()
+		}
+		return prompts[i].Card.Question < prompts[j].Card.Question
+	})
+	return prompts
+}
+
+// InDirection keeps just the prompts studied in the given direction, or all of them if no direction is given.
+func InDirection(prompts []Prompt, direction string) []Prompt {
+	if direction != FORWARD && direction != REVERSE {
+		return prompts
+	}
+	filtered := make([]Prompt, 0, len(prompts))
+	for _, prompt := range prompts {
+		if prompt.Reversed == (direction == REVERSE) {
+			filtered = append(filtered, prompt)
+		}
+	}
+	return filtered
+}
+
+// GetPrompt finds the prompt with the given key, which will have an empty card if there is no such prompt.
+func (deck *Deck) GetPrompt(key string) Prompt {
+	cardID, cloze, reversed := ParsePromptKey(key)
+	return NewPrompt(deck.GetCard(cardID), cloze, reversed)
+}
+
+// NewPrompt returns one of the card's prompts, defaulting to the first deletion of a cloze card.
+func NewPrompt(card Card, cloze int, reversed bool) Prompt {
+	if !card.IsCloze() {
+		return Prompt{Card: card, Reversed: reversed}
+	}
+	if cloze == 0 {
+		numbers := ClozeNumbers(card.Question)
+		if len(numbers) > 0 {
+			cloze = numbers[0]
+		}
+	}
+	return Prompt{Card: card, Cloze: cloze}
+}
+
+// Question is the markdown text shown to the learner.
+func (prompt Prompt) Question() string {
+	if prompt.Card.IsCloze() {
+		return ClozeQuestion(prompt.Card.Question, prompt.Cloze)
+	}
+	if prompt.Reversed {
+		return prompt.Card.Answer
+	}
+	return prompt.Card.Question
+}
+
+// Answer is the markdown text revealed to the learner.
+func (prompt Prompt) Answer() string {
+	if prompt.Card.IsCloze() {
+		answer := ClozeAnswer(prompt.Card.Question, prompt.Cloze)
+		if prompt.Card.Answer != "" {
+			answer = answer + "\n\n" + prompt.Card.Answer
+		}
+		return answer
+	}
+	if prompt.Reversed {
+		return prompt.Card.Question
+	}
+	return prompt.Card.Answer
+}
+
+// AcceptableAnswers lists the answers accepted when the learner types their answer.
+func (prompt Prompt) AcceptableAnswers() []string {
+	if prompt.Card.IsCloze() {
+		return []string{strings.Join(ClozeTerms(prompt.Card.Question, prompt.Cloze), " ")}
+	}
+	if prompt.Reversed {
+		return []string{prompt.Card.Question}
+	}
+	return prompt.Card.AcceptableAnswers()
+}
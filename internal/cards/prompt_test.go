@@ -0,0 +1,50 @@
+package cards
+
+import "testing"
+
+func TestReversiblePrompts(t *testing.T) {
+	yes := true
+	no := false
+	deck := Deck{ID: "TEST-CODE", Reversible: true}
+	deck.PutCard("1", Card{ID: "1", Question: "Q1", Answer: "A1"})
+	deck.PutCard("2", Card{ID: "2", Question: "Q2", Answer: "A2", Reversible: &no})
+
+	if len(deck.Prompts()) != 3 {
+		t.Errorf("Unexpected prompts with deck default: %v", deck.Prompts())
+	}
+
+	deck.Reversible = false
+	deck.PutCard("2", Card{ID: "2", Question: "Q2", Answer: "A2", Reversible: &yes})
+	if len(deck.Prompts()) != 3 {
+		t.Errorf("Unexpected prompts with card setting: %v", deck.Prompts())
+	}
+
+	reversed := InDirection(deck.Prompts(), REVERSE)
+	if len(reversed) != 1 || reversed[0].Key() != "2.r" {
+		t.Fatalf("Unexpected reversed prompts: %v", reversed)
+	}
+	if reversed[0].Question() != "A2" || reversed[0].Answer() != "Q2" {
+		t.Errorf("Reversed prompt not swapped: %s / %s", reversed[0].Question(), reversed[0].Answer())
+	}
+	if len(InDirection(deck.Prompts(), FORWARD)) != 2 {
+		t.Errorf("Unexpected forward prompts")
+	}
+}
+
+func TestParsePromptKey(t *testing.T) {
+	id, cloze, reversed := ParsePromptKey("ABC.r")
+	if id != "ABC" || cloze != 0 || !reversed {
+		t.Errorf("Unexpected parse of reversed key: %s %d %v", id, cloze, reversed)
+	}
+	id, cloze, reversed = ParsePromptKey("ABC.c3")
+	if id != "ABC" || cloze != 3 || reversed {
+		t.Errorf("Unexpected parse of cloze key: %s %d %v", id, cloze, reversed)
+	}
+}
+
+func TestClozeCardsNotReversible(t *testing.T) {
+	card := Card{Type: CLOZE_CARD}
+	if card.IsReversible(true) {
+		t.Errorf("Cloze card should not be reversible")
+	}
+}
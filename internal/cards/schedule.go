@@ -0,0 +1,169 @@
+package cards
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Grade is the learner's own assessment of how well they recalled a card.
+type Grade int
+
+const (
+	Again Grade = iota
+	Hard
+	Good
+	Easy
+)
+
+const DEFAULT_EASE = 2.5
+const MINIMUM_EASE = 1.3
+
+// Cards graded "Again" come back round after a short delay rather than a day later
+const AGAIN_DELAY = time.Minute
+
+// Review holds the SM-2 scheduling state of a single card for a single learner.
+type Review struct {
+	CardID      string
+	Ease        float64
+	Interval    int // days
+	Repetitions int
+	Due         time.Time
+	Reviewed    time.Time
+	Box         int // Leitner box, used by the Leitner study mode
+}
+
+// Progress holds all of a learner's reviews for one deck.
+type Progress struct {
+	LearnerID string
+	DeckID    string
+	Reviews   map[string]Review
+}
+
+func ParseGrade(text string) (Grade, bool) {
+	switch text {
+	case "0", "again":
+		return Again, true
+	case "1", "hard":
+		return Hard, true
+	case "2", "good":
+		return Good, true
+	case "3", "easy":
+		return Easy, true
+	}
+	return Again, false
+}
+
+func NewReview(cardID string) Review {
+	return Review{
+		CardID: cardID,
+		Ease:   DEFAULT_EASE,
+	}
+}
+
+// Grade updates the review using the SM-2 algorithm, with the four grades
+// mapped onto SM-2 response qualities 2 (Again) to 5 (Easy).
+func (review *Review) Grade(grade Grade, now time.Time) {
+	if review.Ease == 0 {
+		review.Ease = DEFAULT_EASE
+	}
+
+	quality := float64(grade) + 2
+
+	if grade == Again {
+		review.Repetitions = 0
+		review.Interval = 0
+	} else {
+		review.Repetitions++
+		switch review.Repetitions {
+		case 1:
+			review.Interval = 1
+		case 2:
+			review.Interval = 6
+		default:
+			review.Interval = int(math.Round(float64(review.Interval) * review.Ease))
+		}
+	}
+
+	review.Ease = review.Ease + (0.1 - (5-quality)*(0.08+(5-quality)*0.02))
+	if review.Ease < MINIMUM_EASE {
+		review.Ease = MINIMUM_EASE
+	}
+
+	if review.Interval == 0 {
+		review.Due = now.Add(AGAIN_DELAY)
+	} else {
+		review.Due = now.AddDate(0, 0, review.Interval)
+	}
+	review.Reviewed = now
+}
+
+// IsScheduled reports whether the review has been graded, as other study modes record their own
+// state in the same review without giving it a due time
+func (review Review) IsScheduled() bool {
+	return !review.Due.IsZero()
+}
+
+func (progress *Progress) GetReview(key string) Review {
+	review, ok := progress.Reviews[key]
+	if !ok {
+		review = NewReview(key)
+	}
+	return review
+}
+
+// Grade records a review of the prompt with the given key.
+func (progress *Progress) Grade(key string, grade Grade, now time.Time) {
+	review := progress.GetReview(key)
+	review.Grade(grade, now)
+	if progress.Reviews == nil {
+		progress.Reviews = make(map[string]Review)
+	}
+	progress.Reviews[key] = review
+}
+
+// NextDue picks the prompt that the learner should review next: the most overdue
+// prompt if any are due, otherwise a prompt that has never been graded, otherwise
+// the prompt that will become due soonest.
+func (progress *Progress) NextDue(prompts []Prompt, now time.Time) Prompt {
+	var overdue, soonest *Prompt
+	var overdueDue, soonestDue time.Time
+	unseen := make([]Prompt, 0)
+
+	for _, prompt := range prompts {
+		prompt := prompt
+		review, ok := progress.Reviews[prompt.Key()]
+		if !ok || !review.IsScheduled() {
+			unseen = append(unseen, prompt)
+		} else if !review.Due.After(now) {
+			if overdue == nil || review.Due.Before(overdueDue) {
+				overdue, overdueDue = &prompt, review.Due
+			}
+		} else if soonest == nil || review.Due.Before(soonestDue) {
+			soonest, soonestDue = &prompt, review.Due
+		}
+	}
+
+	if overdue != nil {
+		return *overdue
+	}
+	if len(unseen) > 0 {
+		return unseen[rand.Intn(len(unseen))]
+	}
+	if soonest != nil {
+		return *soonest
+	}
+	return Prompt{Card: Card{ID: "ERROR"}}
+}
+
+// DueCount returns the number of prompts that are due for review, including unseen prompts.
+func (progress *Progress) DueCount(prompts []Prompt, now time.Time) int {
+	count := 0
+	for _, prompt := range prompts {
+		review, ok := progress.Reviews[prompt.Key()]
+		if !ok || !review.IsScheduled() || !review.Due.After(now) {
+			count++
+		}
+	}
+	return count
+}
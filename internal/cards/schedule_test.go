@@ -0,0 +1,129 @@
+package cards
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGradeIntervals(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	review := NewReview("1")
+
+	review.Grade(Good, now)
+	if review.Interval != 1 {
+		t.Errorf("Unexpected first interval: %d", review.Interval)
+	}
+	review.Grade(Good, now)
+	if review.Interval != 6 {
+		t.Errorf("Unexpected second interval: %d", review.Interval)
+	}
+	review.Grade(Good, now)
+	if review.Interval != 15 {
+		t.Errorf("Unexpected third interval: %d", review.Interval)
+	}
+	if !review.Due.Equal(now.AddDate(0, 0, 15)) {
+		t.Errorf("Unexpected due date: %v", review.Due)
+	}
+}
+
+func TestGradeAgain(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	review := NewReview("1")
+	review.Grade(Easy, now)
+	review.Grade(Easy, now)
+
+	review.Grade(Again, now)
+
+	if review.Repetitions != 0 || review.Interval != 0 {
+		t.Errorf("Again did not reset review: %+v", review)
+	}
+	if !review.Due.Equal(now.Add(AGAIN_DELAY)) {
+		t.Errorf("Unexpected due time after Again: %v", review.Due)
+	}
+}
+
+func TestEaseHasMinimum(t *testing.T) {
+	review := NewReview("1")
+	for i := 0; i < 20; i++ {
+		review.Grade(Again, time.Now())
+	}
+	if review.Ease != MINIMUM_EASE {
+		t.Errorf("Unexpected ease after repeated failures: %f", review.Ease)
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	grade, ok := ParseGrade("3")
+	if !ok || grade != Easy {
+		t.Errorf("Unexpected grade parsed: %d", grade)
+	}
+	if _, ok := ParseGrade("7"); ok {
+		t.Errorf("Invalid grade was parsed")
+	}
+}
+
+func TestNextDuePrefersOverdue(t *testing.T) {
+	now := time.Now()
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1"})
+	deck.PutCard("2", Card{ID: "2"})
+	deck.PutCard("3", Card{ID: "3"})
+
+	progress := Progress{Reviews: map[string]Review{
+		"1": {CardID: "1", Due: now.Add(time.Hour)},
+		"2": {CardID: "2", Due: now.Add(-time.Hour)},
+		"3": {CardID: "3", Due: now.Add(-time.Minute)},
+	}}
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "2" {
+		t.Errorf("Expected most overdue card, got %s", prompt.Key())
+	}
+	if progress.DueCount(deck.Prompts(), now) != 2 {
+		t.Errorf("Unexpected due count: %d", progress.DueCount(deck.Prompts(), now))
+	}
+}
+
+func TestNextDueUnseenThenSoonest(t *testing.T) {
+	now := time.Now()
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1"})
+	deck.PutCard("2", Card{ID: "2"})
+
+	progress := Progress{}
+	progress.Grade("1", Good, now)
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "2" {
+		t.Errorf("Expected unseen card, got %s", prompt.Key())
+	}
+
+	progress.Grade("2", Easy, now)
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "1" {
+		t.Errorf("Expected soonest due card, got %s", prompt.Key())
+	}
+}
+
+func TestNextDueIgnoresUngradedReviews(t *testing.T) {
+	now := time.Now()
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1"})
+	deck.PutCard("2", Card{ID: "2"})
+
+	progress := Progress{Reviews: map[string]Review{
+		"1": {CardID: "1", Due: now.Add(-time.Hour)},
+		"2": {CardID: "2", Box: 3, Reviewed: now},
+	}}
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "1" {
+		t.Errorf("Expected overdue card before ungraded one, got %s", prompt.Key())
+	}
+
+	progress.Grade("1", Easy, now)
+
+	if prompt := progress.NextDue(deck.Prompts(), now); prompt.Key() != "2" {
+		t.Errorf("Expected ungraded card to count as unseen, got %s", prompt.Key())
+	}
+	if progress.DueCount(deck.Prompts(), now) != 1 {
+		t.Errorf("Unexpected due count: %d", progress.DueCount(deck.Prompts(), now))
+	}
+}
@@ -0,0 +1,126 @@
+package cards
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Outcome records how a learner did with a card during a study session.
+type Outcome string
+
+const (
+	Right  Outcome = "right"
+	Wrong  Outcome = "wrong"
+	Hinted Outcome = "hint"
+)
+
+// Session walks through a set of prompts exactly once, in a shuffled order.
+type Session struct {
+	ID       string
+	DeckID   string
+	Order    []string
+	Position int
+	Results  map[string]Outcome
+	Started  time.Time
+}
+
+type SessionResult struct {
+	Prompt  Prompt
+	Outcome Outcome
+}
+
+func RandomSessionId() string {
+	return fmt.Sprintf("%08X%08X", rand.Intn(0xFFFFFFFF), rand.Intn(0xFFFFFFFF))
+}
+
+// NewSession starts a session covering each of the given prompts.
+func NewSession(deckID string, prompts []Prompt) Session {
+	keys := make([]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		keys = append(keys, prompt.Key())
+	}
+	return NewSessionForKeys(deckID, keys)
+}
+
+// NewSessionForKeys starts a session covering the prompts with the given keys, such as those missed in an earlier session.
+func NewSessionForKeys(deckID string, keys []string) Session {
+	order := make([]string, len(keys))
+	copy(order, keys)
+	rand.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+
+	return Session{
+		ID:      RandomSessionId(),
+		DeckID:  deckID,
+		Order:   order,
+		Results: make(map[string]Outcome),
+		Started: time.Now(),
+	}
+}
+
+// Current returns the key of the prompt to be studied next, or an empty string once the session is finished.
+func (session Session) Current() string {
+	if session.Finished() {
+		return ""
+	}
+	return session.Order[session.Position]
+}
+
+// Number is the 1-based position of the current prompt, for display.
+func (session Session) Number() int {
+	return session.Position + 1
+}
+
+func (session Session) Finished() bool {
+	return session.Position >= len(session.Order)
+}
+
+// Record stores the outcome for the current prompt and moves on to the next one.
+// Outcomes for any other prompt are ignored, so that revisiting an old page cannot skip prompts.
+func (session *Session) Record(key string, outcome Outcome) bool {
+	if session.Finished() || session.Current() != key {
+		return false
+	}
+	if session.Results == nil {
+		session.Results = make(map[string]Outcome)
+	}
+	session.Results[key] = outcome
+	session.Position++
+	return true
+}
+
+func (session Session) Count(outcome Outcome) int {
+	count := 0
+	for _, result := range session.Results {
+		if result == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+// Missed returns the keys of prompts answered wrongly, in the order they were studied.
+func (session Session) Missed() []string {
+	missed := make([]string, 0)
+	for _, id := range session.Order {
+		if session.Results[id] == Wrong {
+			missed = append(missed, id)
+		}
+	}
+	return missed
+}
+
+// Summary lists each studied prompt in order along with its outcome.
+func (session Session) Summary(deck Deck) []SessionResult {
+	results := make([]SessionResult, 0)
+	for _, key := range session.Order {
+		outcome, ok := session.Results[key]
+		if !ok {
+			continue
+		}
+		results = append(results, SessionResult{Prompt: deck.GetPrompt(key), Outcome: outcome})
+	}
+	return results
+}
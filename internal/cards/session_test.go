@@ -0,0 +1,59 @@
+package cards
+
+import "testing"
+
+func TestSessionCoversEveryCardOnce(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.AddCard(Card{Question: "1"})
+	deck.AddCard(Card{Question: "2"})
+	deck.AddCard(Card{Question: "3"})
+
+	session := NewSession(deck.ID, deck.Prompts())
+
+	seen := make(map[string]bool)
+	for !session.Finished() {
+		id := session.Current()
+		if seen[id] {
+			t.Fatalf("Card %s seen twice", id)
+		}
+		seen[id] = true
+		session.Record(id, Right)
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("Unexpected number of cards studied: %d", len(seen))
+	}
+	if session.Current() != "" {
+		t.Errorf("Finished session still has a current card")
+	}
+}
+
+func TestSessionIgnoresOtherCards(t *testing.T) {
+	session := NewSessionForKeys("TEST-CODE", []string{"1", "2"})
+	other := "1"
+	if session.Current() == "1" {
+		other = "2"
+	}
+
+	if session.Record(other, Right) {
+		t.Errorf("Recorded outcome for a card that is not current")
+	}
+	if session.Position != 0 {
+		t.Errorf("Session advanced after ignored outcome")
+	}
+}
+
+func TestSessionMissedAndCounts(t *testing.T) {
+	session := NewSessionForKeys("TEST-CODE", []string{"1", "2", "3"})
+	outcomes := []Outcome{Wrong, Hinted, Wrong}
+	for _, outcome := range outcomes {
+		session.Record(session.Current(), outcome)
+	}
+
+	if len(session.Missed()) != 2 {
+		t.Errorf("Unexpected missed cards: %v", session.Missed())
+	}
+	if session.Count(Hinted) != 1 || session.Count(Right) != 0 {
+		t.Errorf("Unexpected outcome counts: %v", session.Results)
+	}
+}
@@ -0,0 +1,81 @@
+package cards
+
+import (
+	"sort"
+	"strings"
+)
+
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// ParseTags splits a comma separated list of tags, tidying them up and removing duplicates.
+func ParseTags(text string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func (card Card) HasTag(tag string) bool {
+	for _, t := range card.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTags reports whether the card carries every one of the given tags.
+func (card Card) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !card.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// TagList is the card's tags as they are entered on the edit form.
+func (card Card) TagList() string {
+	return strings.Join(card.Tags, ", ")
+}
+
+// TagCounts lists every tag used in the deck, in alphabetical order, with the number of cards carrying it.
+func (deck *Deck) TagCounts() []TagCount {
+	counts := make(map[string]int)
+	for _, card := range deck.Cards {
+		for _, tag := range card.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// WithTags keeps just the prompts whose cards carry all of the given tags.
+func WithTags(prompts []Prompt, tags []string) []Prompt {
+	if len(tags) == 0 {
+		return prompts
+	}
+	filtered := make([]Prompt, 0, len(prompts))
+	for _, prompt := range prompts {
+		if prompt.Card.HasTags(tags) {
+			filtered = append(filtered, prompt)
+		}
+	}
+	return filtered
+}
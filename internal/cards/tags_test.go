@@ -0,0 +1,40 @@
+package cards
+
+import "testing"
+
+func TestParseTags(t *testing.T) {
+	tags := ParseTags(" Verbs, irregular  Verbs,,verbs, Past")
+
+	if len(tags) != 3 || tags[0] != "verbs" || tags[1] != "irregular verbs" || tags[2] != "past" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
+
+func TestTagCounts(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1", Tags: []string{"b", "a"}})
+	deck.PutCard("2", Card{ID: "2", Tags: []string{"b"}})
+
+	counts := deck.TagCounts()
+
+	if len(counts) != 2 || counts[0] != (TagCount{"a", 1}) || counts[1] != (TagCount{"b", 2}) {
+		t.Errorf("Unexpected tag counts: %v", counts)
+	}
+}
+
+func TestWithTags(t *testing.T) {
+	deck := Deck{ID: "TEST-CODE"}
+	deck.PutCard("1", Card{ID: "1", Tags: []string{"b", "a"}})
+	deck.PutCard("2", Card{ID: "2", Tags: []string{"b"}})
+	deck.PutCard("3", Card{ID: "3"})
+
+	if len(WithTags(deck.Prompts(), []string{"b"})) != 2 {
+		t.Errorf("Unexpected prompts tagged b")
+	}
+	if len(WithTags(deck.Prompts(), []string{"a", "b"})) != 1 {
+		t.Errorf("Unexpected prompts tagged a and b")
+	}
+	if len(WithTags(deck.Prompts(), nil)) != 3 {
+		t.Errorf("Prompts filtered without any tags")
+	}
+}
@@ -0,0 +1,61 @@
+package cards
+
+import "time"
+
+// How long deleted cards and decks are kept in the trash before they are purged
+const TRASH_RETENTION = 30 * 24 * time.Hour
+
+// TrashItem is a deleted card or deck, kept so that it can be restored until it expires.
+type TrashItem struct {
+	ID      string // the ID of the deleted card or deck
+	DeckID  string // the deck whose trash the item is shown in
+	Card    *Card  // set when a card was deleted
+	Deck    *Deck  // set when a whole deck was deleted
+	Deleted time.Time
+	Expires time.Time
+}
+
+// TrashCard wraps a card deleted from its deck.
+func TrashCard(card Card, now time.Time) TrashItem {
+	return TrashItem{
+		ID:      card.ID,
+		DeckID:  card.DeckID,
+		Card:    &card,
+		Deleted: now,
+		Expires: now.Add(TRASH_RETENTION),
+	}
+}
+
+// TrashDeck wraps a deleted deck, which is shown in its parent's trash if it is a sub-deck, otherwise in its own.
+func TrashDeck(deck Deck, now time.Time) TrashItem {
+	deckID := deck.ParentID
+	if deckID == "" {
+		deckID = deck.ID
+	}
+	return TrashItem{
+		ID:      deck.ID,
+		DeckID:  deckID,
+		Deck:    &deck,
+		Deleted: now,
+		Expires: now.Add(TRASH_RETENTION),
+	}
+}
+
+func (item TrashItem) IsDeck() bool {
+	return item.Deck != nil
+}
+
+// Title describes the item in the trash listing.
+func (item TrashItem) Title() string {
+	if item.Deck != nil {
+		return item.Deck.Title
+	}
+	if item.Card != nil {
+		return item.Card.Question
+	}
+	return item.ID
+}
+
+func (item TrashItem) IsExpired(now time.Time) bool {
+	return !now.Before(item.Expires)
+}
@@ -0,0 +1,38 @@
+package cards
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrashCard(t *testing.T) {
+	now := time.Now()
+	item := TrashCard(Card{ID: "C1", DeckID: "D1", Question: "Q"}, now)
+
+	if item.ID != "C1" || item.DeckID != "D1" || item.IsDeck() {
+		t.Errorf("Unexpected trash item %+v", item)
+	}
+	if item.Title() != "Q" {
+		t.Errorf("Unexpected title %s", item.Title())
+	}
+	if item.IsExpired(now.Add(TRASH_RETENTION - time.Second)) {
+		t.Error("Item expired before the end of the retention period")
+	}
+	if !item.IsExpired(now.Add(TRASH_RETENTION)) {
+		t.Error("Item has not expired at the end of the retention period")
+	}
+}
+
+func TestTrashDeck(t *testing.T) {
+	now := time.Now()
+
+	item := TrashDeck(Deck{ID: "D1", Title: "Top"}, now)
+	if item.DeckID != "D1" || !item.IsDeck() || item.Title() != "Top" {
+		t.Errorf("Unexpected trash item %+v", item)
+	}
+
+	item = TrashDeck(Deck{ID: "D2", Title: "Sub", ParentID: "D1"}, now)
+	if item.DeckID != "D1" {
+		t.Errorf("Sub-deck should be in its parent's trash, got %s", item.DeckID)
+	}
+}
@@ -0,0 +1,56 @@
+package cards
+
+// DeckTree is a deck along with all of its sub-decks.
+type DeckTree struct {
+	Deck     Deck
+	Children []DeckTree
+}
+
+// BuildTree assembles the tree of sub-decks below the deck, using the children function to find each deck's sub-decks.
+func BuildTree(deck Deck, children func(parentID string) []Deck) DeckTree {
+	return buildTree(deck, children, map[string]bool{})
+}
+
+func buildTree(deck Deck, children func(parentID string) []Deck, visited map[string]bool) DeckTree {
+	visited[deck.ID] = true
+	tree := DeckTree{Deck: deck, Children: make([]DeckTree, 0)}
+	for _, child := range children(deck.ID) {
+		// Guard against a deck being its own ancestor
+		if visited[child.ID] {
+			continue
+		}
+		tree.Children = append(tree.Children, buildTree(child, children, visited))
+	}
+	return tree
+}
+
+// CardCount is the number of cards in the deck and all of its sub-decks.
+func (tree DeckTree) CardCount() int {
+	count := len(tree.Deck.Cards)
+	for _, child := range tree.Children {
+		count += child.CardCount()
+	}
+	return count
+}
+
+// StudyDeck combines the cards of the deck and all of its sub-decks into one deck for studying.
+// Cards keep the ID of the deck they belong to, and the reversible setting of their own deck.
+func (tree DeckTree) StudyDeck() Deck {
+	deck := tree.Deck
+	deck.Cards = make(map[string]Card)
+	tree.collectCards(deck.Cards)
+	return deck
+}
+
+func (tree DeckTree) collectCards(into map[string]Card) {
+	for id, card := range tree.Deck.Cards {
+		if card.Reversible == nil {
+			reversible := tree.Deck.Reversible
+			card.Reversible = &reversible
+		}
+		into[id] = card
+	}
+	for _, child := range tree.Children {
+		child.collectCards(into)
+	}
+}
@@ -0,0 +1,78 @@
+package cards
+
+import "testing"
+
+func testDecks() map[string]Deck {
+	subject := Deck{ID: "SUBJECT"}
+	subject.PutCard("S1", Card{ID: "S1"})
+
+	chapter1 := Deck{ID: "CH1", ParentID: "SUBJECT", Reversible: true}
+	chapter1.PutCard("C1", Card{ID: "C1"})
+	chapter1.PutCard("C2", Card{ID: "C2"})
+
+	chapter2 := Deck{ID: "CH2", ParentID: "SUBJECT"}
+	chapter2.PutCard("C3", Card{ID: "C3"})
+
+	section := Deck{ID: "SEC", ParentID: "CH2"}
+	section.PutCard("C4", Card{ID: "C4"})
+
+	return map[string]Deck{"SUBJECT": subject, "CH1": chapter1, "CH2": chapter2, "SEC": section}
+}
+
+func childrenOf(decks map[string]Deck) func(string) []Deck {
+	return func(parentID string) []Deck {
+		children := make([]Deck, 0)
+		for _, deck := range decks {
+			if deck.ParentID == parentID {
+				children = append(children, deck)
+			}
+		}
+		return children
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	decks := testDecks()
+
+	tree := BuildTree(decks["SUBJECT"], childrenOf(decks))
+
+	if len(tree.Children) != 2 {
+		t.Errorf("Unexpected number of children: %d", len(tree.Children))
+	}
+	if tree.CardCount() != 5 {
+		t.Errorf("Unexpected total card count: %d", tree.CardCount())
+	}
+}
+
+func TestBuildTreeWithCycle(t *testing.T) {
+	decks := testDecks()
+	subject := decks["SUBJECT"]
+	subject.ParentID = "SEC"
+	decks["SUBJECT"] = subject
+
+	tree := BuildTree(decks["SUBJECT"], childrenOf(decks))
+
+	if tree.CardCount() != 5 {
+		t.Errorf("Unexpected total card count with cycle: %d", tree.CardCount())
+	}
+}
+
+func TestStudyDeck(t *testing.T) {
+	decks := testDecks()
+
+	deck := BuildTree(decks["SUBJECT"], childrenOf(decks)).StudyDeck()
+
+	if deck.ID != "SUBJECT" || len(deck.Cards) != 5 {
+		t.Errorf("Unexpected study deck: %s with %d cards", deck.ID, len(deck.Cards))
+	}
+	if deck.Cards["C4"].DeckID != "SEC" {
+		t.Errorf("Card lost its own deck ID: %s", deck.Cards["C4"].DeckID)
+	}
+	// Two cards from the reversible chapter add a reversed prompt each
+	if len(deck.Prompts()) != 7 {
+		t.Errorf("Unexpected number of prompts: %d", len(deck.Prompts()))
+	}
+	if len(decks["SUBJECT"].Cards) != 1 {
+		t.Errorf("Building the study deck changed the original deck")
+	}
+}
@@ -0,0 +1,104 @@
+package cards
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MIN_USER_NAME_LENGTH = 3
+const MAX_USER_NAME_LENGTH = 30
+
+// bcrypt only looks at the first 72 bytes of a password, so longer ones are refused rather than
+// quietly cut short
+const MIN_PASSWORD_LENGTH = 8
+const MAX_PASSWORD_LENGTH = 72
+
+// How long someone stays logged in without logging in again
+const LOGIN_DURATION = 30 * 24 * time.Hour
+
+var userNamePattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+
+// User is someone who can create and edit decks, having registered with an invite code.
+type User struct {
+	Name         string // normalised, and never changes
+	PasswordHash string // bcrypt, including its salt and cost
+	InvitedBy    string // a hash of the invite code used to register, which does not give the code away
+	Created      time.Time
+}
+
+// Login is a server-side login session. The person logged in holds a random token, and the login is
+// stored under a hash of the token so that reading the store does not let anyone log in.
+type Login struct {
+	ID       string
+	UserName string
+	Created  time.Time
+	Expires  time.Time
+}
+
+// NormaliseUserName tidies up a user name as typed, ready for CheckUserName.
+func NormaliseUserName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CheckUserName explains why a normalised user name cannot be registered, or returns nil if it can.
+func CheckUserName(name string) error {
+	if len(name) < MIN_USER_NAME_LENGTH || len(name) > MAX_USER_NAME_LENGTH {
+		return errors.New("user names must be between 3 and 30 characters long")
+	}
+	if !userNamePattern.MatchString(name) {
+		return errors.New("user names can only have lowercase letters and numbers, with single dots, hyphens or underscores between them")
+	}
+	return nil
+}
+
+// CheckPassword explains why a new password is not allowed, or returns nil if it is.
+func CheckPassword(password string) error {
+	if len(password) < MIN_PASSWORD_LENGTH || len(password) > MAX_PASSWORD_LENGTH {
+		return errors.New("passwords must be between 8 and 72 characters long")
+	}
+	return nil
+}
+
+// SetPassword stores a salted hash of the password, never the password itself.
+func (user *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	return nil
+}
+
+// HasPassword reports whether the password is the user's one.
+func (user User) HasPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// NewLogin starts a login session for the user, returning the token that identifies it.
+func NewLogin(userName string, now time.Time) (Login, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Login{}, "", err
+	}
+	token := hex.EncodeToString(secret)
+	login := Login{
+		ID:       LoginID(token),
+		UserName: userName,
+		Created:  now,
+		Expires:  now.Add(LOGIN_DURATION),
+	}
+	return login, token, nil
+}
+
+// LoginID is the ID a login is stored under, given its token.
+func LoginID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
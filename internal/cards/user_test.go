@@ -0,0 +1,61 @@
+package cards
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckUserName(t *testing.T) {
+	for _, name := range []string{"ann", "mr.smith", "year-7_teacher", "a1b"} {
+		if err := CheckUserName(name); err != nil {
+			t.Errorf("Expected %q to be allowed, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "ab", "Ann", "two words", "-ann", "ann--b", strings.Repeat("a", 31)} {
+		if CheckUserName(name) == nil {
+			t.Errorf("Expected %q to be refused", name)
+		}
+	}
+	if NormaliseUserName("  Ann ") != "ann" {
+		t.Errorf("Unexpected normalised name %q", NormaliseUserName("  Ann "))
+	}
+}
+
+func TestPasswords(t *testing.T) {
+	if CheckPassword("short") == nil || CheckPassword(strings.Repeat("x", 73)) == nil {
+		t.Error("Expected short and overlong passwords to be refused")
+	}
+
+	var user User
+	if err := user.SetPassword("correct horse"); err != nil {
+		t.Fatalf("Failed to set password: %v", err)
+	}
+	if strings.Contains(user.PasswordHash, "correct horse") {
+		t.Error("Password stored without hashing")
+	}
+	if !user.HasPassword("correct horse") || user.HasPassword("Correct horse") || user.HasPassword("") {
+		t.Error("Password check gave the wrong answer")
+	}
+	if (User{}).HasPassword("") {
+		t.Error("User without a password accepted an empty one")
+	}
+}
+
+func TestNewLogin(t *testing.T) {
+	now := time.Now()
+	login, token, err := NewLogin("ann", now)
+	if err != nil {
+		t.Fatalf("Failed to start login: %v", err)
+	}
+	if login.ID == token || login.ID != LoginID(token) {
+		t.Errorf("Login should be stored under a hash of its token, got %s for %s", login.ID, token)
+	}
+	if login.UserName != "ann" || !login.Expires.Equal(now.Add(LOGIN_DURATION)) {
+		t.Errorf("Unexpected login %+v", login)
+	}
+	other, _, _ := NewLogin("ann", now)
+	if other.ID == login.ID {
+		t.Error("Expected each login to have its own token")
+	}
+}
@@ -0,0 +1,736 @@
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+	"flashcards/internal/schema"
+)
+
+// The sub-directories holding each kind of document, one JSON file per document
+const DECK_DIR = "decks"
+const PROGRESS_DIR = "progress"
+const SESSION_DIR = "sessions"
+const TRASH_DIR = "trash"
+const CHANGE_DIR = "changes"
+const USER_DIR = "users"
+const LOGIN_DIR = "logins"
+
+// The file holding the author keys, mapping each key to its role
+const KEYS_FILE = "keys.json"
+
+// The file holding the deck slugs, mapping each slug to the ID of the deck that claimed it
+const SLUGS_FILE = "slugs.json"
+
+const LOCK_FILE = ".lock"
+
+// FileDataStore keeps each deck in its own JSON file within a directory. Writes go to a temporary
+// file which is then renamed into place, so a crash never leaves a half-written deck, and a lock
+// file keeps concurrent requests and other processes from seeing each other's partial changes.
+type FileDataStore struct {
+	Dir  string
+	Err  error
+	logs platform.Logger
+	mu   sync.RWMutex
+	lock *os.File
+
+	// readers counts the requests sharing the file lock, which only the last of them may drop
+	readersMu sync.Mutex
+	readers   int
+}
+
+func NewFileDataStore(logs platform.Logger, dir string) *FileDataStore {
+	return &FileDataStore{Dir: dir, logs: logs}
+}
+
+func (store *FileDataStore) Summary() string {
+	return fmt.Sprintf("FileDataStore(%s)", store.Dir)
+}
+
+// Init creates the data directories if they do not exist yet.
+func (store *FileDataStore) Init(ctx context.Context) {
+	for _, dir := range []string{DECK_DIR, PROGRESS_DIR, SESSION_DIR, TRASH_DIR, CHANGE_DIR, USER_DIR, LOGIN_DIR} {
+		if store.Err = os.MkdirAll(filepath.Join(store.Dir, dir), 0755); store.Err != nil {
+			store.logs.Error(ctx, "Failed to create data directory: %v", store.Err)
+			return
+		}
+	}
+	store.lock, store.Err = os.OpenFile(filepath.Join(store.Dir, LOCK_FILE), os.O_CREATE|os.O_RDWR, 0644)
+	if store.Err != nil {
+		store.logs.Error(ctx, "Failed to open lock file: %v", store.Err)
+		return
+	}
+	store.logs.Info(ctx, "Initialised file store in %s", store.Dir)
+}
+
+func (store *FileDataStore) Close() error {
+	return store.lock.Close()
+}
+
+// acquire locks the store for reading or writing, returning the function that releases the lock
+func (store *FileDataStore) acquire(exclusive bool) (func(), error) {
+	if store.lock == nil {
+		return nil, fmt.Errorf("%w: %v", platform.ErrUnavailable, store.Err)
+	}
+	if exclusive {
+		store.mu.Lock()
+		if err := lockFile(store.lock, true); err != nil {
+			store.mu.Unlock()
+			return nil, fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+		}
+		return func() {
+			unlockFile(store.lock)
+			store.mu.Unlock()
+		}, nil
+	}
+
+	// Readers in this process share one file descriptor, and so one file lock
+	store.mu.RLock()
+	store.readersMu.Lock()
+	if store.readers == 0 {
+		if err := lockFile(store.lock, false); err != nil {
+			store.readersMu.Unlock()
+			store.mu.RUnlock()
+			return nil, fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+		}
+	}
+	store.readers++
+	store.readersMu.Unlock()
+	return func() {
+		store.readersMu.Lock()
+		store.readers--
+		if store.readers == 0 {
+			unlockFile(store.lock)
+		}
+		store.readersMu.Unlock()
+		store.mu.RUnlock()
+	}, nil
+}
+
+// docPath is the file for a document, refusing IDs that could reach outside the store's directory
+func (store *FileDataStore) docPath(dir string, id string) (string, error) {
+	if id == "" || strings.HasPrefix(id, ".") || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("invalid document ID %q %w", id, platform.ErrNotFound)
+	}
+	return filepath.Join(store.Dir, dir, id+".json"), nil
+}
+
+// readFile decodes a JSON file, which must be done while holding the lock
+func readFile(path string, into any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s %w", filepath.Base(path), platform.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
+
+// writeFile encodes a JSON file by writing a temporary file and renaming it over the original,
+// which must be done while holding the exclusive lock
+func writeFile(path string, from any) error {
+	data, err := json.MarshalIndent(from, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+func removeFile(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s %w", filepath.Base(path), platform.ErrNotFound)
+	}
+	return err
+}
+
+func (store *FileDataStore) get(dir string, id string, into any) error {
+	path, err := store.docPath(dir, id)
+	if err != nil {
+		return err
+	}
+	release, err := store.acquire(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return readFile(path, into)
+}
+
+func (store *FileDataStore) put(dir string, id string, from any) error {
+	path, err := store.docPath(dir, id)
+	if err != nil {
+		return err
+	}
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return writeFile(path, from)
+}
+
+func (store *FileDataStore) remove(dir string, id string) error {
+	path, err := store.docPath(dir, id)
+	if err != nil {
+		return err
+	}
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return removeFile(path)
+}
+
+// list reads every document in the directory, passing each one to the decode function
+func (store *FileDataStore) list(dir string, decode func(data []byte) error) error {
+	release, err := store.acquire(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	paths, err := filepath.Glob(filepath.Join(store.Dir, dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := decode(data); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// deckFile is how a deck is kept on disk, with the schema version it was written at
+type deckFile struct {
+	cards.Deck
+	SchemaVersion int
+}
+
+// decodeDeck reads a deck file, upgrading it to the current schema version on the way, and
+// reports whether it needed upgrading
+func decodeDeck(data []byte) (cards.Deck, bool, error) {
+	var deck cards.Deck
+	doc, err := schema.Decode(data)
+	if err != nil {
+		return deck, false, err
+	}
+	id, _ := doc["ID"].(string)
+	upgraded, err := schema.UpgradeDeck(id, doc)
+	if err != nil {
+		return deck, false, err
+	}
+	return deck, upgraded, doc.To(&deck)
+}
+
+// readDeckFile reads a deck file, which must be done while holding the lock
+func readDeckFile(path string) (cards.Deck, error) {
+	var data json.RawMessage
+	if err := readFile(path, &data); err != nil {
+		return cards.Deck{}, err
+	}
+	deck, _, err := decodeDeck(data)
+	if err != nil {
+		return deck, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return deck, nil
+}
+
+// writeDeckFile writes a deck file at the current schema version, which must be done while
+// holding the exclusive lock
+func writeDeckFile(path string, deck cards.Deck) error {
+	return writeFile(path, deckFile{Deck: deck, SchemaVersion: schema.CurrentVersion()})
+}
+
+func (store *FileDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	store.logs.Debug(ctx, "Reading deck file %s", id)
+	path, err := store.docPath(DECK_DIR, id)
+	if err != nil {
+		return cards.Deck{}, err
+	}
+	release, err := store.acquire(false)
+	if err != nil {
+		return cards.Deck{}, err
+	}
+	defer release()
+	return readDeckFile(path)
+}
+
+// PutDeck writes the deck file as long as the deck on disk is still at the version that was read.
+// Cards in the file that are not in the deck being written are kept.
+func (store *FileDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	store.logs.Info(ctx, "Writing deck file %s at version %d", id, deck.Version)
+
+	path, err := store.docPath(DECK_DIR, id)
+	if err != nil {
+		return err
+	}
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	current, err := readDeckFile(path)
+	if err == nil && current.Version != deck.Version {
+		return fmt.Errorf("deck %s is at version %d not %d, %w", id, current.Version, deck.Version, platform.ErrConflict)
+	} else if err != nil && !errors.Is(err, platform.ErrNotFound) {
+		return err
+	}
+
+	written := current
+	if err != nil {
+		written.Created, written.CreatedBy = deck.Created, deck.CreatedBy
+		if written.Created.IsZero() {
+			written.Created = time.Now().UTC()
+		}
+	}
+	written.ID, written.Title, written.Reversible, written.ParentID, written.Slug = deck.ID, deck.Title, deck.Reversible, deck.ParentID, deck.Slug
+	written.Version = deck.Version + 1
+	for cardID, card := range deck.Cards {
+		card.Version++
+		written.PutCard(cardID, card)
+	}
+	return writeDeckFile(path, written)
+}
+
+// changeDeckFile reads a deck file, applies the change and writes it back while holding the lock.
+// Every card lives in its deck's file, so this is how single cards are changed.
+func (store *FileDataStore) changeDeckFile(id string, change func(deck *cards.Deck) error) error {
+	path, err := store.docPath(DECK_DIR, id)
+	if err != nil {
+		return err
+	}
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	deck, err := readDeckFile(path)
+	if err != nil {
+		return err
+	}
+	if err := change(&deck); err != nil {
+		return err
+	}
+	return writeDeckFile(path, deck)
+}
+
+func (store *FileDataStore) GetCard(ctx context.Context, deckID string, cardID string) (cards.Card, error) {
+	deck, err := store.GetDeck(ctx, deckID)
+	if err != nil {
+		return cards.Card{}, err
+	}
+	card, ok := deck.Cards[cardID]
+	if !ok {
+		return card, fmt.Errorf("card %s in deck %s %w", cardID, deckID, platform.ErrNotFound)
+	}
+	return card, nil
+}
+
+// PutCard writes a card into its deck file as long as the card on disk is still at the version that was read.
+func (store *FileDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	store.logs.Info(ctx, "Writing card %s to deck file %s at version %d", card.ID, deckID, card.Version)
+	return store.changeDeckFile(deckID, func(deck *cards.Deck) error {
+		if current, ok := deck.Cards[card.ID]; ok && current.Version != card.Version {
+			return fmt.Errorf("card %s is at version %d not %d, %w", card.ID, current.Version, card.Version, platform.ErrConflict)
+		}
+		card.DeckID = deckID
+		card.Version++
+		deck.PutCard(card.ID, card)
+		return nil
+	})
+}
+
+func (store *FileDataStore) DeleteCard(ctx context.Context, deckID string, cardID string) error {
+	store.logs.Info(ctx, "Deleting card %s from deck file %s", cardID, deckID)
+	return store.changeDeckFile(deckID, func(deck *cards.Deck) error {
+		if _, ok := deck.Cards[cardID]; !ok {
+			return fmt.Errorf("card %s in deck %s %w", cardID, deckID, platform.ErrNotFound)
+		}
+		delete(deck.Cards, cardID)
+		return nil
+	})
+}
+
+func (store *FileDataStore) ListCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	list := make([]cards.Card, 0)
+	deck, err := store.GetDeck(ctx, deckID)
+	if err != nil {
+		return list, err
+	}
+	for _, card := range deck.Cards {
+		list = append(list, card)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (store *FileDataStore) GetChildDecks(ctx context.Context, parentID string) ([]cards.Deck, error) {
+	children := make([]cards.Deck, 0)
+	err := store.list(DECK_DIR, func(data []byte) error {
+		deck, _, err := decodeDeck(data)
+		if err != nil {
+			return err
+		}
+		if deck.ParentID == parentID {
+			children = append(children, deck)
+		}
+		return nil
+	})
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Title < children[j].Title
+	})
+	return children, err
+}
+
+// ListDecks reads every deck file, so is only meant for occasional use
+func (store *FileDataStore) ListDecks(ctx context.Context, query platform.DeckQuery) (platform.DeckPage, error) {
+	decks := make([]platform.DeckSummary, 0)
+	err := store.list(DECK_DIR, func(data []byte) error {
+		deck, _, err := decodeDeck(data)
+		if err != nil {
+			return err
+		}
+		decks = append(decks, platform.SummariseDeck(deck))
+		return nil
+	})
+	if err != nil {
+		return platform.DeckPage{}, err
+	}
+	return platform.PageDecks(decks, query)
+}
+
+func (store *FileDataStore) DeleteDeck(ctx context.Context, id string) error {
+	store.logs.Info(ctx, "Deleting deck file %s", id)
+	return store.remove(DECK_DIR, id)
+}
+
+// UpgradeSchema rewrites every deck file that was written at an older schema version
+func (store *FileDataStore) UpgradeSchema(ctx context.Context) (int, error) {
+	release, err := store.acquire(true)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	paths, err := filepath.Glob(filepath.Join(store.Dir, DECK_DIR, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return count, err
+		}
+		deck, upgraded, err := decodeDeck(data)
+		if err != nil {
+			return count, fmt.Errorf("%s: %v", path, err)
+		}
+		if !upgraded {
+			continue
+		}
+		store.logs.Info(ctx, "Upgrading deck file %s to schema version %d", deck.ID, schema.CurrentVersion())
+		if err := writeDeckFile(path, deck); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (store *FileDataStore) IsEmpty() bool {
+	release, err := store.acquire(false)
+	if err != nil {
+		// A store that cannot be read is not seeded, in case it has decks
+		return false
+	}
+	defer release()
+	paths, _ := filepath.Glob(filepath.Join(store.Dir, DECK_DIR, "*.json"))
+	return len(paths) == 0
+}
+
+// authorKeys reads the author key file, which is treated as empty if it does not exist yet
+func (store *FileDataStore) authorKeys() (map[string]string, error) {
+	keys := make(map[string]string)
+	err := readFile(filepath.Join(store.Dir, KEYS_FILE), &keys)
+	if errors.Is(err, platform.ErrNotFound) {
+		return keys, nil
+	}
+	return keys, err
+}
+
+func (store *FileDataStore) IsValidAuthor(key string) bool {
+	release, err := store.acquire(false)
+	if err != nil {
+		return false
+	}
+	defer release()
+
+	keys, err := store.authorKeys()
+	if err != nil {
+		store.logs.Error(context.Background(), "Failed to read author keys: %v", err)
+		return false
+	}
+	role, ok := keys[strings.TrimSpace(key)]
+	if !ok {
+		store.logs.Info(context.Background(), "Author key not found")
+		return false
+	}
+	if role != "author" {
+		store.logs.Info(context.Background(), "Key does not have author role")
+		return false
+	}
+	return true
+}
+
+func (store *FileDataStore) GetAuthorKeys(ctx context.Context) (map[string]string, error) {
+	release, err := store.acquire(false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return store.authorKeys()
+}
+
+// PutAuthorKey adds or updates a key in the author key file.
+func (store *FileDataStore) PutAuthorKey(ctx context.Context, key string, role string) error {
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	keys, err := store.authorKeys()
+	if err != nil {
+		return err
+	}
+	keys[strings.TrimSpace(key)] = role
+	return writeFile(filepath.Join(store.Dir, KEYS_FILE), keys)
+}
+
+func progressDocID(learnerID string, deckID string) string {
+	return learnerID + "_" + deckID
+}
+
+func (store *FileDataStore) GetProgress(ctx context.Context, learnerID string, deckID string) (cards.Progress, error) {
+	progress := cards.Progress{LearnerID: learnerID, DeckID: deckID}
+	err := store.get(PROGRESS_DIR, progressDocID(learnerID, deckID), &progress)
+	if errors.Is(err, platform.ErrNotFound) {
+		store.logs.Debug(ctx, "No progress found for learner %s on deck %s", learnerID, deckID)
+		return cards.Progress{LearnerID: learnerID, DeckID: deckID}, nil
+	}
+	return progress, err
+}
+
+func (store *FileDataStore) PutProgress(ctx context.Context, progress cards.Progress) error {
+	store.logs.Debug(ctx, "Writing progress for learner %s on deck %s", progress.LearnerID, progress.DeckID)
+	return store.put(PROGRESS_DIR, progressDocID(progress.LearnerID, progress.DeckID), progress)
+}
+
+func (store *FileDataStore) GetSession(ctx context.Context, id string) (cards.Session, error) {
+	var session cards.Session
+	err := store.get(SESSION_DIR, id, &session)
+	return session, err
+}
+
+func (store *FileDataStore) PutSession(ctx context.Context, session cards.Session) error {
+	store.logs.Debug(ctx, "Writing study session %s", session.ID)
+	return store.put(SESSION_DIR, session.ID, session)
+}
+
+func (store *FileDataStore) GetTrash(ctx context.Context, deckID string) ([]cards.TrashItem, error) {
+	items := make([]cards.TrashItem, 0)
+	err := store.list(TRASH_DIR, func(data []byte) error {
+		var item cards.TrashItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+		if item.DeckID == deckID {
+			items = append(items, item)
+		}
+		return nil
+	})
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Deleted.After(items[j].Deleted)
+	})
+	return items, err
+}
+
+func (store *FileDataStore) GetTrashItem(ctx context.Context, id string) (cards.TrashItem, error) {
+	var item cards.TrashItem
+	err := store.get(TRASH_DIR, id, &item)
+	return item, err
+}
+
+func (store *FileDataStore) PutTrashItem(ctx context.Context, item cards.TrashItem) error {
+	store.logs.Debug(ctx, "Writing trash item %s", item.ID)
+	return store.put(TRASH_DIR, item.ID, item)
+}
+
+func (store *FileDataStore) DeleteTrashItem(ctx context.Context, id string) error {
+	store.logs.Debug(ctx, "Deleting trash item %s", id)
+	err := store.remove(TRASH_DIR, id)
+	if errors.Is(err, platform.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (store *FileDataStore) GetChanges(ctx context.Context, deckID string, cardID string) ([]cards.Change, error) {
+	changes := make([]cards.Change, 0)
+	err := store.list(CHANGE_DIR, func(data []byte) error {
+		var change cards.Change
+		if err := json.Unmarshal(data, &change); err != nil {
+			return err
+		}
+		if change.DeckID == deckID && (cardID == "" || change.CardID == cardID) {
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID > changes[j].ID
+	})
+	return changes, err
+}
+
+func (store *FileDataStore) PutChange(ctx context.Context, change cards.Change) error {
+	store.logs.Debug(ctx, "Writing change %s to deck %s", change.ID, change.DeckID)
+	return store.put(CHANGE_DIR, change.ID, change)
+}
+
+// slugs reads the slug file, which is treated as empty if it does not exist yet
+func (store *FileDataStore) slugs() (map[string]string, error) {
+	slugs := make(map[string]string)
+	err := readFile(filepath.Join(store.Dir, SLUGS_FILE), &slugs)
+	if errors.Is(err, platform.ErrNotFound) {
+		return slugs, nil
+	}
+	return slugs, err
+}
+
+func (store *FileDataStore) GetSlug(ctx context.Context, slug string) (string, error) {
+	release, err := store.acquire(false)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	slugs, err := store.slugs()
+	if err != nil {
+		return "", err
+	}
+	deckID, ok := slugs[slug]
+	if !ok {
+		return "", fmt.Errorf("slug %s %w", slug, platform.ErrNotFound)
+	}
+	return deckID, nil
+}
+
+// PutSlug adds a slug to the slug file, unless another deck has already claimed it.
+func (store *FileDataStore) PutSlug(ctx context.Context, slug string, deckID string) error {
+	store.logs.Info(ctx, "Claiming slug %s for deck %s", slug, deckID)
+
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	slugs, err := store.slugs()
+	if err != nil {
+		return err
+	}
+	if current, ok := slugs[slug]; ok && current != deckID {
+		return fmt.Errorf("slug %s belongs to deck %s, %w", slug, current, platform.ErrConflict)
+	}
+	slugs[slug] = deckID
+	return writeFile(filepath.Join(store.Dir, SLUGS_FILE), slugs)
+}
+
+func (store *FileDataStore) GetUser(ctx context.Context, name string) (cards.User, error) {
+	var user cards.User
+	err := store.get(USER_DIR, name, &user)
+	return user, err
+}
+
+// PutUser writes a new user's file, unless there already is one with that name.
+func (store *FileDataStore) PutUser(ctx context.Context, user cards.User) error {
+	store.logs.Info(ctx, "Adding user %s", user.Name)
+
+	path, err := store.docPath(USER_DIR, user.Name)
+	if err != nil {
+		return err
+	}
+	release, err := store.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var current cards.User
+	if err := readFile(path, &current); err == nil {
+		return fmt.Errorf("user %s already registered, %w", user.Name, platform.ErrConflict)
+	} else if !errors.Is(err, platform.ErrNotFound) {
+		return err
+	}
+	return writeFile(path, user)
+}
+
+func (store *FileDataStore) GetLogin(ctx context.Context, id string) (cards.Login, error) {
+	var login cards.Login
+	err := store.get(LOGIN_DIR, id, &login)
+	return login, err
+}
+
+func (store *FileDataStore) PutLogin(ctx context.Context, login cards.Login) error {
+	store.logs.Debug(ctx, "Writing login for user %s", login.UserName)
+	return store.put(LOGIN_DIR, login.ID, login)
+}
+
+func (store *FileDataStore) DeleteLogin(ctx context.Context, id string) error {
+	err := store.remove(LOGIN_DIR, id)
+	if errors.Is(err, platform.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,307 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+	"flashcards/internal/schema"
+	"flashcards/internal/test"
+)
+
+func testStore(t *testing.T) *FileDataStore {
+	store := NewFileDataStore(&test.LogRecorder{}, t.TempDir())
+	store.Init(context.Background())
+	if store.Err != nil {
+		t.Fatalf("Failed to initialise store: %v", store.Err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestDeckFiles(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	if !store.IsEmpty() {
+		t.Error("New store should be empty")
+	}
+
+	deck := cards.Deck{ID: "D1", Title: "Deck"}
+	deck.AddCard(cards.Card{ID: "C1", Question: "Q1", Tags: []string{"t"}})
+	if err := store.PutDeck(ctx, deck.ID, deck); err != nil {
+		t.Fatalf("Failed to write deck: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.Dir, DECK_DIR, "D1.json")); err != nil {
+		t.Errorf("Deck file not written: %v", err)
+	}
+	temps, _ := filepath.Glob(filepath.Join(store.Dir, DECK_DIR, ".tmp-*"))
+	if len(temps) != 0 {
+		t.Errorf("Temporary files left behind: %v", temps)
+	}
+
+	read, err := store.GetDeck(ctx, "D1")
+	if err != nil || read.Title != "Deck" || read.Cards["C1"].Tags[0] != "t" {
+		t.Errorf("Unexpected deck %+v, %v", read, err)
+	}
+
+	// A new store on the same directory sees the deck, as after a restart
+	reopened := NewFileDataStore(&test.LogRecorder{}, store.Dir)
+	reopened.Init(ctx)
+	defer reopened.Close()
+	if _, err := reopened.GetDeck(ctx, "D1"); err != nil {
+		t.Errorf("Deck not found after reopening: %v", err)
+	}
+
+	if err := store.DeleteDeck(ctx, "D1"); err != nil {
+		t.Errorf("Failed to delete deck: %v", err)
+	}
+	if _, err := store.GetDeck(ctx, "D1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestInvalidIDs(t *testing.T) {
+	store := testStore(t)
+
+	for _, id := range []string{"", "../keys", ".lock", "a/b"} {
+		if _, err := store.GetDeck(context.Background(), id); !errors.Is(err, platform.ErrNotFound) {
+			t.Errorf("Expected not found error for %q, got %v", id, err)
+		}
+	}
+}
+
+func TestChildDecks(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	store.PutDeck(ctx, "P", cards.Deck{ID: "P", Title: "Parent"})
+	store.PutDeck(ctx, "B", cards.Deck{ID: "B", Title: "Beta", ParentID: "P"})
+	store.PutDeck(ctx, "A", cards.Deck{ID: "A", Title: "Alpha", ParentID: "P"})
+
+	children, err := store.GetChildDecks(ctx, "P")
+	if err != nil || len(children) != 2 || children[0].Title != "Alpha" {
+		t.Errorf("Unexpected child decks %v, %v", children, err)
+	}
+}
+
+func TestAuthorKeyFile(t *testing.T) {
+	store := testStore(t)
+
+	if store.IsValidAuthor("secret") {
+		t.Error("Unknown key accepted")
+	}
+	store.PutAuthorKey(context.Background(), "secret", "author")
+	if !store.IsValidAuthor("secret") {
+		t.Error("Author key not accepted")
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, KEYS_FILE)); err != nil {
+		t.Errorf("Key file not written: %v", err)
+	}
+}
+
+func TestProgressSessionsAndTrash(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	progress, err := store.GetProgress(ctx, "L", "D")
+	if err != nil || progress.LearnerID != "L" {
+		t.Errorf("Unexpected new progress %+v, %v", progress, err)
+	}
+	progress.Grade("C1", cards.Good, time.Now())
+	store.PutProgress(ctx, progress)
+	progress, _ = store.GetProgress(ctx, "L", "D")
+	if progress.GetReview("C1").Repetitions != 1 {
+		t.Errorf("Progress not saved: %+v", progress)
+	}
+
+	session := cards.NewSessionForKeys("D", []string{"C1"})
+	store.PutSession(ctx, session)
+	if read, err := store.GetSession(ctx, session.ID); err != nil || len(read.Order) != 1 {
+		t.Errorf("Unexpected session %+v, %v", read, err)
+	}
+
+	store.PutTrashItem(ctx, cards.TrashCard(cards.Card{ID: "C1", DeckID: "D"}, time.Now()))
+	if items, err := store.GetTrash(ctx, "D"); err != nil || len(items) != 1 {
+		t.Errorf("Unexpected trash %+v, %v", items, err)
+	}
+	store.DeleteTrashItem(ctx, "C1")
+	if _, err := store.GetTrashItem(ctx, "C1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestCards(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	if err := store.PutCard(ctx, "MISSING", cards.Card{ID: "C1"}); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error for a missing deck, got %v", err)
+	}
+
+	deck := cards.Deck{ID: "D1", Title: "Deck"}
+	deck.AddCard(cards.Card{ID: "C1", Question: "Q1"})
+	store.PutDeck(ctx, "D1", deck)
+
+	if err := store.PutCard(ctx, "D1", cards.Card{ID: "C2", Question: "Q2"}); err != nil {
+		t.Fatalf("Failed to add card: %v", err)
+	}
+	card, err := store.GetCard(ctx, "D1", "C2")
+	if err != nil || card.Question != "Q2" || card.DeckID != "D1" || card.Version != 1 {
+		t.Errorf("Unexpected card %+v, %v", card, err)
+	}
+
+	stale := card
+	card.Question = "Changed"
+	if err := store.PutCard(ctx, "D1", card); err != nil {
+		t.Errorf("Failed to change card: %v", err)
+	}
+	if err := store.PutCard(ctx, "D1", stale); !errors.Is(err, platform.ErrConflict) {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+
+	// Writing the deck's settings leaves cards it does not include alone
+	read, _ := store.GetDeck(ctx, "D1")
+	read.Title = "Renamed"
+	read.Cards = nil
+	store.PutDeck(ctx, "D1", read)
+
+	list, err := store.ListCards(ctx, "D1")
+	if err != nil || len(list) != 2 || list[0].ID != "C1" || list[1].Question != "Changed" {
+		t.Errorf("Unexpected cards %+v, %v", list, err)
+	}
+
+	if err := store.DeleteCard(ctx, "D1", "C1"); err != nil {
+		t.Errorf("Failed to delete card: %v", err)
+	}
+	if _, err := store.GetCard(ctx, "D1", "C1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if err := store.DeleteCard(ctx, "D1", "C1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if _, err := store.ListCards(ctx, "MISSING"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+	store.PutDeck(ctx, "D1", cards.Deck{ID: "D1", Title: "Deck"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				deck, err := store.GetDeck(ctx, "D1")
+				if err != nil {
+					t.Errorf("Failed to read deck during writes: %v", err)
+					return
+				}
+				deck.AddCard(cards.Card{Question: "Q"})
+				err = store.PutDeck(ctx, "D1", deck)
+				if err == nil {
+					return
+				} else if !errors.Is(err, platform.ErrConflict) {
+					t.Errorf("Failed to write deck: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	deck, _ := store.GetDeck(ctx, "D1")
+	if deck.Version != 21 || len(deck.Cards) != 20 {
+		t.Errorf("Expected every write to be kept, got version %d with %d cards", deck.Version, len(deck.Cards))
+	}
+}
+
+func TestDeckVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	store.PutDeck(ctx, "D1", cards.Deck{ID: "D1", Title: "First"})
+	stale, _ := store.GetDeck(ctx, "D1")
+	store.PutDeck(ctx, "D1", stale)
+
+	if err := store.PutDeck(ctx, "D1", stale); !errors.Is(err, platform.ErrConflict) {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	test.RunDataStoreConformance(t, func(t *testing.T) platform.DataStore {
+		return testStore(t)
+	})
+}
+
+func TestSchemaUpgrade(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	// A deck file from before decks had a schema version, with cards only known by their key
+	legacy := `{"ID": "D1", "Title": "Old", "Cards": {"C1": {"Question": "Q1", "Tags": ["Old Tag"]}}}`
+	path := filepath.Join(store.Dir, DECK_DIR, "D1.json")
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deck, err := store.GetDeck(ctx, "D1")
+	card := deck.Cards["C1"]
+	if err != nil || card.ID != "C1" || card.DeckID != "D1" || !card.HasTag("old tag") {
+		t.Errorf("Deck not upgraded on read: %+v, %v", deck, err)
+	}
+
+	count, err := store.UpgradeSchema(ctx)
+	if err != nil || count != 1 {
+		t.Errorf("Expected 1 deck upgraded, got %d, %v", count, err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), fmt.Sprintf(`"SchemaVersion": %d`, schema.CurrentVersion())) || !strings.Contains(string(data), `"DeckID": "D1"`) {
+		t.Errorf("Deck file not rewritten: %s", data)
+	}
+
+	if count, err := store.UpgradeSchema(ctx); err != nil || count != 0 {
+		t.Errorf("Expected nothing left to upgrade, got %d, %v", count, err)
+	}
+}
+
+func TestSeedOnlyOnce(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+	test.SetupTestData(ctx, store, &test.LogRecorder{})
+
+	deck, _ := store.GetDeck(ctx, "TEST-CODE")
+	deck.Title = "Edited"
+	deck.Cards = nil
+	store.PutDeck(ctx, "TEST-CODE", deck)
+
+	// Starting again on the same directory leaves the edited deck alone
+	reopened := NewFileDataStore(&test.LogRecorder{}, store.Dir)
+	reopened.Init(ctx)
+	defer reopened.Close()
+	if reopened.IsEmpty() {
+		t.Fatal("Store with decks reported as empty")
+	}
+	logs := &test.LogRecorder{}
+	test.SetupTestData(ctx, reopened, logs)
+	if logs.HasEntryWithPrefix("ERROR") {
+		t.Error("Seeding a store with decks logged an error")
+	}
+	if deck, _ := reopened.GetDeck(ctx, "TEST-CODE"); deck.Title != "Edited" {
+		t.Errorf("Seeding again replaced an edited deck: %s", deck.Title)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package filestore
+
+import "os"
+
+// lockFile does nothing where advisory file locks are not available, so only requests
+// within the one process are kept apart
+func lockFile(file *os.File, exclusive bool) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestSharedLockHeldUntilLastReader(t *testing.T) {
+	store := testStore(t)
+	other, err := os.Open(filepath.Join(store.Dir, LOCK_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	// tryWrite takes the lock as another process would, reporting whether it was free
+	tryWrite := func() bool {
+		if err := syscall.Flock(int(other.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			return false
+		}
+		syscall.Flock(int(other.Fd()), syscall.LOCK_UN)
+		return true
+	}
+
+	first, err := store.acquire(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.acquire(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first()
+	if tryWrite() {
+		t.Error("File lock dropped while a reader was still inside")
+	}
+	second()
+	if !tryWrite() {
+		t.Error("File lock kept after the last reader released it")
+	}
+}
@@ -0,0 +1,32 @@
+package filestore
+
+import (
+	"context"
+
+	"flashcards/internal/platform"
+)
+
+// LocalFilePlatform runs the application on a developer's machine, keeping its data in JSON files
+// so that it survives restarts.
+type LocalFilePlatform struct {
+	logs  platform.ConsoleLogger
+	store *FileDataStore
+}
+
+func FilePlatform(ctx context.Context, dir string) *LocalFilePlatform {
+	p := LocalFilePlatform{}
+	p.store = NewFileDataStore(&p.logs, dir)
+	return &p
+}
+
+func (platform *LocalFilePlatform) Logger() platform.Logger {
+	return &platform.logs
+}
+
+func (platform *LocalFilePlatform) DataStore() platform.DataStore {
+	return platform.store
+}
+
+func (platform *LocalFilePlatform) ListenAddress() string {
+	return "127.0.0.1:8080"
+}
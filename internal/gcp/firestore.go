@@ -2,19 +2,57 @@ package gcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"flashcards/internal/cards"
 	"flashcards/internal/platform"
+	"flashcards/internal/schema"
 )
 
 const DECK_COLLECTION = "Decks"
 const KEYS_COLLECTION = "Keys"
+const PROGRESS_COLLECTION = "Progress"
+const SESSION_COLLECTION = "Sessions"
+const TRASH_COLLECTION = "Trash"
+const CHANGE_COLLECTION = "Changes"
+const SLUG_COLLECTION = "Slugs"
+const USER_COLLECTION = "Users"
+const LOGIN_COLLECTION = "Logins"
+
+// Each deck's cards are documents in a subcollection of the deck document
+const CARD_COLLECTION = "Cards"
+
+// deckDocument is how a deck is kept in Firestore. Decks written before cards had their own
+// documents have the cards embedded in Cards instead, until they are migrated.
+type deckDocument struct {
+	ID            string
+	Title         string
+	Reversible    bool
+	ParentID      string
+	Version       int
+	Created       time.Time
+	Slug          string
+	CreatedBy     string
+	SchemaVersion int
+	Cards         map[string]cards.Card `firestore:",omitempty"`
+}
+
+// cardDocument is how a card is kept in Firestore, with the schema version it was written at
+type cardDocument struct {
+	cards.Card
+	SchemaVersion int
+}
 
 type FireDataStore struct {
 	Client   *firestore.Client
@@ -56,34 +94,522 @@ func (store *FireDataStore) createClient(ctx context.Context, projectID string,
 	return client, err
 }
 
-func (store *FireDataStore) GetDeck(ctx context.Context, id string) cards.Deck {
-	store.logs.Debug(ctx, "Fetching Firestore deck %s", id)
+// storeError converts a Firestore error into one of the DataStore errors, keeping the original details
+func storeError(err error) error {
+	if err == nil || errors.Is(err, platform.ErrConflict) {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %v", platform.ErrNotFound, err)
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return fmt.Errorf("%w: %v", platform.ErrConflict, err)
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Canceled:
+		return fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+	}
+	return err
+}
+
+func (store *FireDataStore) deckRef(id string) *firestore.DocumentRef {
+	return store.Client.Doc(DECK_COLLECTION + "/" + id)
+}
 
-	var deck cards.Deck
+func (store *FireDataStore) cardRef(deckID string, cardID string) *firestore.DocumentRef {
+	return store.Client.Doc(DECK_COLLECTION + "/" + deckID + "/" + CARD_COLLECTION + "/" + cardID)
+}
 
-	doc := store.Client.Doc(DECK_COLLECTION + "/" + id)
-	deckDoc, err := doc.Get(ctx)
+func (store *FireDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	store.logs.Debug(ctx, "Fetching Firestore deck %s", id)
+
+	deckDoc, err := store.deckRef(id).Get(ctx)
 	if err != nil {
 		store.logs.Error(ctx, "Error fetching deck %s, %v", id, err)
-	} else {
-		store.logs.Debug(ctx, "Found game deck %s", id)
+		return cards.Deck{}, storeError(err)
+	}
+
+	store.logs.Debug(ctx, "Found game deck %s", id)
+	return store.readDeck(ctx, deckDoc)
+}
+
+// decodeDocument reads a document into a struct, first upgrading it if it was written at an older
+// schema version, and reports whether it needed upgrading
+func decodeDocument(snapshot *firestore.DocumentSnapshot, upgrade func(doc schema.Document) (bool, error), into any) (bool, error) {
+	version, err := schema.Version(snapshot.Data())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", snapshot.Ref.Path, err)
+	}
+	if version == schema.CurrentVersion() {
+		return false, snapshot.DataTo(into)
+	}
+	doc := schema.Document(snapshot.Data())
+	if _, err := upgrade(doc); err != nil {
+		return false, fmt.Errorf("%s: %w", snapshot.Ref.Path, err)
+	}
+	return true, doc.To(into)
+}
+
+func decodeDeck(deckDoc *firestore.DocumentSnapshot) (deckDocument, bool, error) {
+	var doc deckDocument
+	upgraded, err := decodeDocument(deckDoc, func(data schema.Document) (bool, error) {
+		return schema.UpgradeDeck(deckDoc.Ref.ID, data)
+	}, &doc)
+	return doc, upgraded, err
+}
+
+func decodeCard(cardDoc *firestore.DocumentSnapshot) (cards.Card, bool, error) {
+	var card cards.Card
+	upgraded, err := decodeDocument(cardDoc, func(data schema.Document) (bool, error) {
+		return schema.UpgradeCard(cardDoc.Ref.Parent.Parent.ID, cardDoc.Ref.ID, data)
+	}, &card)
+	return card, upgraded, err
+}
+
+// readDeck converts a deck document into a deck along with all of its cards, migrating any cards
+// still embedded in the document into their own documents on the way. Documents written at an
+// older schema version are upgraded as they are read, and stay as they are until next written.
+func (store *FireDataStore) readDeck(ctx context.Context, deckDoc *firestore.DocumentSnapshot) (cards.Deck, error) {
+	doc, _, err := decodeDeck(deckDoc)
+	if err != nil {
+		return cards.Deck{}, err
+	}
+	deck := cards.Deck{
+		ID:         doc.ID,
+		Title:      doc.Title,
+		Reversible: doc.Reversible,
+		ParentID:   doc.ParentID,
+		Version:    doc.Version,
+		Created:    doc.Created,
+		Slug:       doc.Slug,
+		CreatedBy:  doc.CreatedBy,
+		Cards:      make(map[string]cards.Card),
+	}
+
+	if len(doc.Cards) > 0 {
+		if err := store.migrateCards(ctx, deckDoc, doc.Cards); err != nil {
+			// The embedded cards are still good to read, the migration is tried again next time
+			store.logs.Error(ctx, "Error migrating cards of deck %s, %v", deckDoc.Ref.ID, err)
+		}
+	}
 
-		deckDoc.DataTo(&deck)
+	cardDocs, err := deckDoc.Ref.Collection(CARD_COLLECTION).Documents(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching cards of deck %s, %v", deckDoc.Ref.ID, err)
+		return cards.Deck{}, storeError(err)
+	}
+	for _, cardDoc := range cardDocs {
+		card, _, err := decodeCard(cardDoc)
+		if err != nil {
+			return cards.Deck{}, err
+		}
+		deck.Cards[cardDoc.Ref.ID] = card
+	}
+	for cardID, card := range doc.Cards {
+		if _, ok := deck.Cards[cardID]; !ok {
+			deck.PutCard(cardID, card)
+		}
 	}
 
-	return deck
+	return deck, nil
 }
 
-func (store *FireDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) {
-	store.logs.Info(ctx, "Writing Firestore deck %s", id)
+// migrateCards moves the cards embedded in a deck document into their own documents, then removes
+// them from the deck document as long as it has not changed since it was read. Cards that already
+// have their own document are not overwritten, so a migration that stops part way can run again.
+func (store *FireDataStore) migrateCards(ctx context.Context, deckDoc *firestore.DocumentSnapshot, embedded map[string]cards.Card) error {
+	deckID := deckDoc.Ref.ID
+	store.logs.Info(ctx, "Migrating %d embedded cards of deck %s", len(embedded), deckID)
+
+	list := make([]cards.Card, 0, len(embedded))
+	for cardID, card := range embedded {
+		card.ID = cardID
+		if card.DeckID == "" {
+			card.DeckID = deckID
+		}
+		list = append(list, card)
+	}
+	if err := store.writeCards(ctx, deckID, list, true); err != nil {
+		return err
+	}
+
+	_, err := deckDoc.Ref.Update(ctx, []firestore.Update{{Path: "Cards", Value: firestore.Delete}},
+		firestore.LastUpdateTime(deckDoc.UpdateTime))
+	return err
+}
+
+// writeCards writes card documents with a BulkWriter, as a transaction is limited to 500 writes.
+// When create is set, cards that already have a document are left as they are.
+func (store *FireDataStore) writeCards(ctx context.Context, deckID string, list []cards.Card, create bool) error {
+	writer := store.Client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(list))
+	for _, card := range list {
+		var job *firestore.BulkWriterJob
+		var err error
+		written := cardDocument{Card: card, SchemaVersion: schema.CurrentVersion()}
+		if create {
+			job, err = writer.Create(store.cardRef(deckID, card.ID), written)
+		} else {
+			job, err = writer.Set(store.cardRef(deckID, card.ID), written)
+		}
+		if err != nil {
+			writer.End()
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	writer.End()
 
-	doc := store.Client.Doc(DECK_COLLECTION + "/" + id)
-	_, err := doc.Set(ctx, deck)
+	for _, job := range jobs {
+		_, err := job.Results()
+		if create && status.Code(err) == codes.AlreadyExists {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PutDeck writes the deck if it is new, or if the stored deck still has the version that was read.
+// New decks are created with the precondition that the document does not exist yet, and existing
+// decks are checked and written in a transaction that fails if the document changes part way. The
+// cards in the deck are written after the deck itself.
+func (store *FireDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	store.logs.Info(ctx, "Writing Firestore deck %s at version %d", id, deck.Version)
+
+	doc := store.deckRef(id)
+	written := deckDocument{
+		ID:            deck.ID,
+		Title:         deck.Title,
+		Reversible:    deck.Reversible,
+		ParentID:      deck.ParentID,
+		Version:       deck.Version + 1,
+		Created:       deck.Created,
+		Slug:          deck.Slug,
+		CreatedBy:     deck.CreatedBy,
+		SchemaVersion: schema.CurrentVersion(),
+	}
+	if written.Created.IsZero() {
+		written.Created = time.Now().UTC()
+	}
+	err := store.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		deckDoc, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return tx.Create(doc, written)
+		}
+		if err != nil {
+			return err
+		}
+
+		var current deckDocument
+		if err := deckDoc.DataTo(&current); err != nil {
+			return err
+		}
+		if current.Version != deck.Version {
+			return fmt.Errorf("deck %s is at version %d not %d, %w", id, current.Version, deck.Version, platform.ErrConflict)
+		}
+		if len(current.Cards) > 0 {
+			// Replacing the document would lose cards that have not been migrated yet
+			return fmt.Errorf("deck %s has cards waiting to be migrated, %w", id, platform.ErrConflict)
+		}
+		written.Created, written.CreatedBy = current.Created, current.CreatedBy
+
+		return tx.Set(doc, written)
+	})
 	if err != nil {
 		store.logs.Error(ctx, "Error writing deck %v", err)
-	} else {
-		store.logs.Debug(ctx, "Wrote deck document %s", id)
+		return storeError(err)
+	}
+
+	list := make([]cards.Card, 0, len(deck.Cards))
+	for cardID, card := range deck.Cards {
+		card.ID = cardID
+		card.DeckID = id
+		card.Version++
+		list = append(list, card)
+	}
+	if err := store.writeCards(ctx, id, list, false); err != nil {
+		store.logs.Error(ctx, "Error writing cards of deck %s, %v", id, err)
+		return storeError(err)
+	}
+
+	store.logs.Debug(ctx, "Wrote deck document %s", id)
+	return nil
+}
+
+// ListDecks reads the fields it needs from every deck document and pages through them itself, as
+// Firestore cannot sort on card counts or match part of a title. Cards are counted with an
+// aggregation query for each deck, so only for the decks on the page unless sorting by card count.
+func (store *FireDataStore) ListDecks(ctx context.Context, query platform.DeckQuery) (platform.DeckPage, error) {
+	if err := query.Check(); err != nil {
+		return platform.DeckPage{}, err
+	}
+
+	deckDocs, err := store.Client.Collection(DECK_COLLECTION).Select("ID", "Title", "ParentID", "Created", "Cards").Documents(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error listing decks, %v", err)
+		return platform.DeckPage{}, storeError(err)
+	}
+	decks := make([]platform.DeckSummary, 0, len(deckDocs))
+	for _, deckDoc := range deckDocs {
+		var doc deckDocument
+		if err := deckDoc.DataTo(&doc); err != nil {
+			return platform.DeckPage{}, err
+		}
+		decks = append(decks, platform.DeckSummary{
+			ID:        deckDoc.Ref.ID,
+			Title:     doc.Title,
+			ParentID:  doc.ParentID,
+			Created:   doc.Created,
+			CardCount: len(doc.Cards),
+		})
+	}
+
+	byCards := query.Sort == platform.SORT_BY_CARDS
+	if byCards {
+		if err := store.countCards(ctx, decks); err != nil {
+			return platform.DeckPage{}, err
+		}
+	}
+	page, err := platform.PageDecks(decks, query)
+	if err == nil && !byCards {
+		err = store.countCards(ctx, page.Decks)
+	}
+	return page, err
+}
+
+// countCards adds the number of card documents to the count of each deck's embedded cards
+func (store *FireDataStore) countCards(ctx context.Context, decks []platform.DeckSummary) error {
+	for i := range decks {
+		result, err := store.deckRef(decks[i].ID).Collection(CARD_COLLECTION).NewAggregationQuery().WithCount("count").Get(ctx)
+		if err != nil {
+			store.logs.Error(ctx, "Error counting cards of deck %s, %v", decks[i].ID, err)
+			return storeError(err)
+		}
+		if count, ok := result["count"].(*firestorepb.Value); ok {
+			decks[i].CardCount += int(count.GetIntegerValue())
+		}
+	}
+	return nil
+}
+
+func (store *FireDataStore) GetChildDecks(ctx context.Context, parentID string) ([]cards.Deck, error) {
+	store.logs.Debug(ctx, "Fetching Firestore child decks of %s", parentID)
+
+	children := make([]cards.Deck, 0)
+
+	query := store.Client.Collection(DECK_COLLECTION).Where("ParentID", "==", parentID).OrderBy("Title", firestore.Asc)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching child decks of %s, %v", parentID, err)
+		return children, storeError(err)
+	}
+
+	for _, doc := range docs {
+		deck, err := store.readDeck(ctx, doc)
+		if err != nil {
+			return children, err
+		}
+		children = append(children, deck)
+	}
+
+	return children, nil
+}
+
+// DeleteDeck removes the deck document and then its cards, as Firestore leaves subcollections
+// behind when a document is deleted.
+func (store *FireDataStore) DeleteDeck(ctx context.Context, id string) error {
+	store.logs.Info(ctx, "Deleting Firestore deck %s", id)
+
+	doc := store.deckRef(id)
+	_, err := doc.Delete(ctx, firestore.Exists)
+	if err != nil {
+		store.logs.Error(ctx, "Error deleting deck %s, %v", id, err)
+		return storeError(err)
+	}
+
+	cardRefs, err := doc.Collection(CARD_COLLECTION).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error listing cards of deleted deck %s, %v", id, err)
+		return storeError(err)
 	}
+	writer := store.Client.BulkWriter(ctx)
+	for _, cardRef := range cardRefs {
+		if _, err := writer.Delete(cardRef); err != nil {
+			writer.End()
+			return storeError(err)
+		}
+	}
+	writer.End()
+	return nil
+}
+
+func (store *FireDataStore) GetCard(ctx context.Context, deckID string, cardID string) (cards.Card, error) {
+	store.logs.Debug(ctx, "Fetching Firestore card %s in deck %s", cardID, deckID)
+
+	var card cards.Card
+	cardDoc, err := store.cardRef(deckID, cardID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		// The card may still be embedded in a deck that has not been migrated
+		deck, err := store.GetDeck(ctx, deckID)
+		if err != nil {
+			return card, err
+		}
+		card, ok := deck.Cards[cardID]
+		if !ok {
+			return card, fmt.Errorf("card %s in deck %s %w", cardID, deckID, platform.ErrNotFound)
+		}
+		return card, nil
+	}
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching card %s in deck %s, %v", cardID, deckID, err)
+		return card, storeError(err)
+	}
+
+	card, _, err = decodeCard(cardDoc)
+	return card, err
+}
+
+// PutCard writes a single card document in a transaction that checks the deck exists and the card
+// still has the version that was read.
+func (store *FireDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	store.logs.Info(ctx, "Writing Firestore card %s in deck %s at version %d", card.ID, deckID, card.Version)
+
+	doc := store.cardRef(deckID, card.ID)
+	written := cardDocument{Card: card, SchemaVersion: schema.CurrentVersion()}
+	written.DeckID = deckID
+	written.Version++
+	err := store.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(store.deckRef(deckID)); err != nil {
+			return err
+		}
+
+		cardDoc, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return tx.Create(doc, written)
+		}
+		if err != nil {
+			return err
+		}
+
+		var current cards.Card
+		if err := cardDoc.DataTo(&current); err != nil {
+			return err
+		}
+		if current.Version != card.Version {
+			return fmt.Errorf("card %s is at version %d not %d, %w", card.ID, current.Version, card.Version, platform.ErrConflict)
+		}
+		return tx.Set(doc, written)
+	})
+	if err != nil {
+		store.logs.Error(ctx, "Error writing card %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) DeleteCard(ctx context.Context, deckID string, cardID string) error {
+	store.logs.Info(ctx, "Deleting Firestore card %s in deck %s", cardID, deckID)
+
+	_, err := store.cardRef(deckID, cardID).Delete(ctx, firestore.Exists)
+	if err != nil {
+		store.logs.Error(ctx, "Error deleting card %s in deck %s, %v", cardID, deckID, err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) ListCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	list := make([]cards.Card, 0)
+
+	deck, err := store.GetDeck(ctx, deckID)
+	if err != nil {
+		return list, err
+	}
+	for _, card := range deck.Cards {
+		list = append(list, card)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// UpgradeSchema rewrites every deck and card document that was written at an older schema version.
+// Each document is only replaced if it has not changed since it was read, so documents written by
+// the server while this runs are left alone, as they are already at the current version.
+func (store *FireDataStore) UpgradeSchema(ctx context.Context) (int, error) {
+	deckDocs, err := store.Client.Collection(DECK_COLLECTION).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, storeError(err)
+	}
+
+	count := 0
+	for _, deckDoc := range deckDocs {
+		doc, upgraded, err := decodeDeck(deckDoc)
+		if err != nil {
+			return count, err
+		}
+		if len(doc.Cards) > 0 {
+			// Reading the deck moves the embedded cards into their own documents at the current version
+			if _, err := store.readDeck(ctx, deckDoc); err != nil {
+				return count, err
+			}
+			continue
+		}
+		if upgraded {
+			store.logs.Info(ctx, "Upgrading deck %s to schema version %d", deckDoc.Ref.ID, schema.CurrentVersion())
+			doc.SchemaVersion = schema.CurrentVersion()
+			replaced, err := store.replaceUnchanged(ctx, deckDoc, doc)
+			if err != nil {
+				return count, err
+			}
+			if replaced {
+				count++
+			}
+		}
+
+		cardDocs, err := deckDoc.Ref.Collection(CARD_COLLECTION).Documents(ctx).GetAll()
+		if err != nil {
+			return count, storeError(err)
+		}
+		for _, cardDoc := range cardDocs {
+			card, upgraded, err := decodeCard(cardDoc)
+			if err != nil {
+				return count, err
+			}
+			if !upgraded {
+				continue
+			}
+			replaced, err := store.replaceUnchanged(ctx, cardDoc, cardDocument{Card: card, SchemaVersion: schema.CurrentVersion()})
+			if err != nil {
+				return count, err
+			}
+			if replaced {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// replaceUnchanged replaces a document in a transaction, as long as it has not been written since
+// the snapshot was read
+func (store *FireDataStore) replaceUnchanged(ctx context.Context, snapshot *firestore.DocumentSnapshot, data any) (bool, error) {
+	replaced := false
+	err := store.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		current, err := tx.Get(snapshot.Ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		replaced = current.UpdateTime.Equal(snapshot.UpdateTime)
+		if !replaced {
+			return nil
+		}
+		return tx.Set(snapshot.Ref, data)
+	})
+	return replaced, storeError(err)
 }
 
 func (store *FireDataStore) IsEmpty() bool {
@@ -105,3 +631,277 @@ func (store *FireDataStore) IsValidAuthor(key string) bool {
 	}
 	return true
 }
+
+func (store *FireDataStore) GetAuthorKeys(ctx context.Context) (map[string]string, error) {
+	keys := make(map[string]string)
+
+	docs, err := store.Client.Collection(KEYS_COLLECTION).Documents(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching author keys, %v", err)
+		return keys, storeError(err)
+	}
+	for _, doc := range docs {
+		role, _ := doc.Data()["role"].(string)
+		keys[doc.Ref.ID] = role
+	}
+	return keys, nil
+}
+
+// PutAuthorKey adds or updates a key that lets people create decks.
+func (store *FireDataStore) PutAuthorKey(ctx context.Context, key string, role string) error {
+	doc := store.Client.Doc(KEYS_COLLECTION + "/" + strings.TrimSpace(key))
+	_, err := doc.Set(ctx, map[string]interface{}{"role": role})
+	if err != nil {
+		store.logs.Error(ctx, "Error writing author key %v", err)
+	}
+	return storeError(err)
+}
+
+func progressDocID(learnerID string, deckID string) string {
+	return learnerID + "_" + deckID
+}
+
+func (store *FireDataStore) GetProgress(ctx context.Context, learnerID string, deckID string) (cards.Progress, error) {
+	progress := cards.Progress{LearnerID: learnerID, DeckID: deckID}
+
+	doc := store.Client.Doc(PROGRESS_COLLECTION + "/" + progressDocID(learnerID, deckID))
+	progressDoc, err := doc.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		store.logs.Debug(ctx, "No progress found for learner %s on deck %s", learnerID, deckID)
+		return progress, nil
+	}
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching progress for learner %s on deck %s, %v", learnerID, deckID, err)
+		return progress, storeError(err)
+	}
+
+	return progress, progressDoc.DataTo(&progress)
+}
+
+func (store *FireDataStore) PutProgress(ctx context.Context, progress cards.Progress) error {
+	store.logs.Debug(ctx, "Writing progress for learner %s on deck %s", progress.LearnerID, progress.DeckID)
+
+	doc := store.Client.Doc(PROGRESS_COLLECTION + "/" + progressDocID(progress.LearnerID, progress.DeckID))
+	_, err := doc.Set(ctx, progress)
+	if err != nil {
+		store.logs.Error(ctx, "Error writing progress %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) GetSession(ctx context.Context, id string) (cards.Session, error) {
+	var session cards.Session
+
+	doc := store.Client.Doc(SESSION_COLLECTION + "/" + id)
+	sessionDoc, err := doc.Get(ctx)
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching session %s, %v", id, err)
+		return session, storeError(err)
+	}
+
+	return session, sessionDoc.DataTo(&session)
+}
+
+func (store *FireDataStore) PutSession(ctx context.Context, session cards.Session) error {
+	store.logs.Debug(ctx, "Writing study session %s", session.ID)
+
+	doc := store.Client.Doc(SESSION_COLLECTION + "/" + session.ID)
+	_, err := doc.Set(ctx, session)
+	if err != nil {
+		store.logs.Error(ctx, "Error writing session %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) GetTrash(ctx context.Context, deckID string) ([]cards.TrashItem, error) {
+	items := make([]cards.TrashItem, 0)
+
+	query := store.Client.Collection(TRASH_COLLECTION).Where("DeckID", "==", deckID).OrderBy("Deleted", firestore.Desc)
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching trash for deck %s, %v", deckID, err)
+		return items, storeError(err)
+	}
+
+	for _, doc := range docs {
+		var item cards.TrashItem
+		if err := doc.DataTo(&item); err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (store *FireDataStore) GetTrashItem(ctx context.Context, id string) (cards.TrashItem, error) {
+	var item cards.TrashItem
+
+	doc := store.Client.Doc(TRASH_COLLECTION + "/" + id)
+	itemDoc, err := doc.Get(ctx)
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching trash item %s, %v", id, err)
+		return item, storeError(err)
+	}
+
+	return item, itemDoc.DataTo(&item)
+}
+
+// PutTrashItem writes a deleted card or deck to the trash. A Firestore TTL policy on the
+// Expires field can be used to remove items that are never restored or purged.
+func (store *FireDataStore) PutTrashItem(ctx context.Context, item cards.TrashItem) error {
+	store.logs.Debug(ctx, "Writing trash item %s", item.ID)
+
+	doc := store.Client.Doc(TRASH_COLLECTION + "/" + item.ID)
+	_, err := doc.Set(ctx, item)
+	if err != nil {
+		store.logs.Error(ctx, "Error writing trash item %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) DeleteTrashItem(ctx context.Context, id string) error {
+	store.logs.Debug(ctx, "Deleting trash item %s", id)
+
+	_, err := store.Client.Doc(TRASH_COLLECTION + "/" + id).Delete(ctx)
+	if err != nil {
+		store.logs.Error(ctx, "Error deleting trash item %s, %v", id, err)
+	}
+	return storeError(err)
+}
+
+// GetChanges only filters with equality, which needs no composite index, and sorts the changes itself
+func (store *FireDataStore) GetChanges(ctx context.Context, deckID string, cardID string) ([]cards.Change, error) {
+	changes := make([]cards.Change, 0)
+
+	query := store.Client.Collection(CHANGE_COLLECTION).Where("DeckID", "==", deckID)
+	if cardID != "" {
+		query = query.Where("CardID", "==", cardID)
+	}
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		store.logs.Error(ctx, "Error fetching changes to deck %s, %v", deckID, err)
+		return changes, storeError(err)
+	}
+
+	for _, doc := range docs {
+		var change cards.Change
+		if err := doc.DataTo(&change); err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID > changes[j].ID
+	})
+
+	return changes, nil
+}
+
+func (store *FireDataStore) PutChange(ctx context.Context, change cards.Change) error {
+	store.logs.Debug(ctx, "Writing change %s to deck %s", change.ID, change.DeckID)
+
+	doc := store.Client.Doc(CHANGE_COLLECTION + "/" + change.ID)
+	_, err := doc.Set(ctx, change)
+	if err != nil {
+		store.logs.Error(ctx, "Error writing change %v", err)
+	}
+	return storeError(err)
+}
+
+// slugDocument is a claimed slug, kept under the slug as its document ID
+type slugDocument struct {
+	DeckID string
+}
+
+func (store *FireDataStore) GetSlug(ctx context.Context, slug string) (string, error) {
+	slugDoc, err := store.Client.Doc(SLUG_COLLECTION + "/" + slug).Get(ctx)
+	if err != nil {
+		return "", storeError(err)
+	}
+	var claim slugDocument
+	if err := slugDoc.DataTo(&claim); err != nil {
+		return "", err
+	}
+	return claim.DeckID, nil
+}
+
+// PutSlug claims a slug for a deck in a transaction, so that two decks cannot claim it at once.
+func (store *FireDataStore) PutSlug(ctx context.Context, slug string, deckID string) error {
+	store.logs.Info(ctx, "Claiming slug %s for deck %s", slug, deckID)
+
+	doc := store.Client.Doc(SLUG_COLLECTION + "/" + slug)
+	err := store.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		slugDoc, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return tx.Create(doc, slugDocument{DeckID: deckID})
+		}
+		if err != nil {
+			return err
+		}
+
+		var current slugDocument
+		if err := slugDoc.DataTo(&current); err != nil {
+			return err
+		}
+		if current.DeckID != deckID {
+			return fmt.Errorf("slug %s belongs to deck %s, %w", slug, current.DeckID, platform.ErrConflict)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, platform.ErrConflict) {
+		store.logs.Error(ctx, "Error claiming slug %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) GetUser(ctx context.Context, name string) (cards.User, error) {
+	var user cards.User
+
+	userDoc, err := store.Client.Doc(USER_COLLECTION + "/" + name).Get(ctx)
+	if err != nil {
+		return user, storeError(err)
+	}
+	return user, userDoc.DataTo(&user)
+}
+
+// PutUser creates the user's document, which Firestore refuses if the name is already registered.
+func (store *FireDataStore) PutUser(ctx context.Context, user cards.User) error {
+	store.logs.Info(ctx, "Adding user %s", user.Name)
+
+	_, err := store.Client.Doc(USER_COLLECTION+"/"+user.Name).Create(ctx, user)
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		store.logs.Error(ctx, "Error adding user %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) GetLogin(ctx context.Context, id string) (cards.Login, error) {
+	var login cards.Login
+
+	loginDoc, err := store.Client.Doc(LOGIN_COLLECTION + "/" + id).Get(ctx)
+	if err != nil {
+		return login, storeError(err)
+	}
+	return login, loginDoc.DataTo(&login)
+}
+
+// PutLogin writes a login session. A Firestore TTL policy on the Expires field can be used to remove
+// logins that are never logged out.
+func (store *FireDataStore) PutLogin(ctx context.Context, login cards.Login) error {
+	store.logs.Debug(ctx, "Writing login for user %s", login.UserName)
+
+	_, err := store.Client.Doc(LOGIN_COLLECTION+"/"+login.ID).Set(ctx, login)
+	if err != nil {
+		store.logs.Error(ctx, "Error writing login %v", err)
+	}
+	return storeError(err)
+}
+
+func (store *FireDataStore) DeleteLogin(ctx context.Context, id string) error {
+	_, err := store.Client.Doc(LOGIN_COLLECTION + "/" + id).Delete(ctx)
+	if err != nil {
+		store.logs.Error(ctx, "Error deleting login, %v", err)
+	}
+	return storeError(err)
+}
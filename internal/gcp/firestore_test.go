@@ -0,0 +1,97 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"flashcards/internal/platform"
+	"flashcards/internal/schema"
+	"flashcards/internal/test"
+)
+
+func TestStoreError(t *testing.T) {
+	if storeError(nil) != nil {
+		t.Error("Expected nil error to stay nil")
+	}
+
+	cases := map[codes.Code]error{
+		codes.NotFound:           platform.ErrNotFound,
+		codes.FailedPrecondition: platform.ErrConflict,
+		codes.AlreadyExists:      platform.ErrConflict,
+		codes.Unavailable:        platform.ErrUnavailable,
+		codes.DeadlineExceeded:   platform.ErrUnavailable,
+	}
+	for code, expected := range cases {
+		err := storeError(status.Error(code, "test"))
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %v, got %v", expected, code, err)
+		}
+	}
+
+	err := storeError(status.Error(codes.PermissionDenied, "test"))
+	if errors.Is(err, platform.ErrNotFound) || errors.Is(err, platform.ErrConflict) || errors.Is(err, platform.ErrUnavailable) {
+		t.Errorf("Unexpected error mapping for permission denied: %v", err)
+	}
+}
+
+// TestConformance runs against a local Firestore emulator, started for example with
+// `gcloud emulators firestore start --host-port=localhost:8081` and FIRESTORE_EMULATOR_HOST=localhost:8081
+func TestConformance(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	test.RunDataStoreConformance(t, func(t *testing.T) platform.DataStore {
+		return emulatorStore(t)
+	})
+}
+
+func emulatorStore(t *testing.T) *FireDataStore {
+	ctx := context.Background()
+	store := fireDataStore(&test.LogRecorder{}, ctx)
+	// The emulator keeps each project apart, so every test gets an empty store
+	store.Project = fmt.Sprintf("conformance-%d", rand.Int63())
+	store.Database = "(default)"
+	store.Init(ctx)
+	if store.Err != nil {
+		t.Fatalf("Failed to connect to the Firestore emulator: %v", store.Err)
+	}
+	t.Cleanup(store.close)
+	return store
+}
+
+func TestSchemaUpgrade(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	ctx := context.Background()
+	store := emulatorStore(t)
+
+	// Documents from before decks and cards had a schema version
+	store.deckRef("D1").Set(ctx, map[string]any{"ID": "D1", "Title": "Old", "Version": 1})
+	store.cardRef("D1", "C1").Set(ctx, map[string]any{"Question": "Q1", "Tags": []any{"Old Tag"}, "Version": 1})
+
+	card, err := store.GetCard(ctx, "D1", "C1")
+	if err != nil || card.ID != "C1" || card.DeckID != "D1" || !card.HasTag("old tag") {
+		t.Errorf("Card not upgraded on read: %+v, %v", card, err)
+	}
+
+	count, err := store.UpgradeSchema(ctx)
+	if err != nil || count != 2 {
+		t.Errorf("Expected deck and card upgraded, got %d, %v", count, err)
+	}
+	cardDoc, _ := store.cardRef("D1", "C1").Get(ctx)
+	if version, _ := schema.Version(cardDoc.Data()); version != schema.CurrentVersion() {
+		t.Errorf("Card document not rewritten: %v", cardDoc.Data())
+	}
+
+	if count, err := store.UpgradeSchema(ctx); err != nil || count != 0 {
+		t.Errorf("Expected nothing left to upgrade, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// The cookie holding the token of the current login. It is strict like the learner cookie, so other
+// sites cannot make requests as the user, even ones that only follow a link.
+const LOGIN_COOKIE = "login"
+
+type userContextKey struct{}
+
+// unknownUser has a password hash to check against when there is no such user, so that logging in
+// takes as long whether or not the name is registered
+var unknownUser = sync.OnceValue(func() cards.User {
+	var user cards.User
+	user.SetPassword("not anybody's password")
+	return user
+})
+
+// keyHash identifies an author key or invite code without giving it away
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%X", sum[:4])
+}
+
+// loadUser finds who is logged in from their login cookie, and makes them the request's user
+func (app *Application) loadUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := app.loggedInUser(r); ok {
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *Application) loggedInUser(r *http.Request) (cards.User, bool) {
+	cookie, err := r.Cookie(LOGIN_COOKIE)
+	if err != nil || cookie.Value == "" {
+		return cards.User{}, false
+	}
+	ctx := requestContext(r)
+
+	login, err := app.dataStore.GetLogin(ctx, cards.LoginID(cookie.Value))
+	if err != nil {
+		if !errors.Is(err, platform.ErrNotFound) {
+			app.logs.Error(ctx, "Failed to read login, %v", err)
+		}
+		return cards.User{}, false
+	}
+	if time.Now().After(login.Expires) {
+		app.logs.Info(ctx, "Login for user %s has expired", login.UserName)
+		app.dataStore.DeleteLogin(ctx, login.ID)
+		return cards.User{}, false
+	}
+
+	user, err := app.dataStore.GetUser(ctx, login.UserName)
+	if err != nil {
+		app.logs.Error(ctx, "Failed to read user %s for login, %v", login.UserName, err)
+		return cards.User{}, false
+	}
+	return user, true
+}
+
+// currentUser is the user logged in for the request, if there is one
+func currentUser(r *http.Request) (cards.User, bool) {
+	user, ok := r.Context().Value(userContextKey{}).(cards.User)
+	return user, ok
+}
+
+// requireUser shows the error page asking for a login unless a user is logged in for the request,
+// as only users can change decks and cards
+func (app *Application) requireUser(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := currentUser(r); !ok {
+		app.showError(w, r, "3001")
+		return false
+	}
+	return true
+}
+
+// pageUser is the logged-in user for pages that show them, nil if nobody is logged in
+func pageUser(r *http.Request) *cards.User {
+	if user, ok := currentUser(r); ok {
+		return &user
+	}
+	return nil
+}
+
+// startLogin logs the user in with a new login, replacing any the browser already had, showing the
+// error page and returning false if it cannot be stored
+func (app *Application) startLogin(w http.ResponseWriter, r *http.Request, userName string) bool {
+	ctx := requestContext(r)
+	app.dropLogin(r)
+
+	login, token, err := cards.NewLogin(userName, time.Now().UTC())
+	if err != nil {
+		app.logs.Error(ctx, "Failed to make login token, %v", err)
+		app.showError(w, r, "5002")
+		return false
+	}
+	if err := app.dataStore.PutLogin(ctx, login); err != nil {
+		app.showStoreError(w, r, err, "1001")
+		return false
+	}
+
+	cookie := http.Cookie{
+		Name:     LOGIN_COOKIE,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(cards.LOGIN_DURATION.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	http.SetCookie(w, &cookie)
+	app.logs.Info(ctx, "Logged in user %s", userName)
+	return true
+}
+
+// dropLogin removes the browser's login from the store, if it has one
+func (app *Application) dropLogin(r *http.Request) {
+	cookie, err := r.Cookie(LOGIN_COOKIE)
+	if err != nil || cookie.Value == "" {
+		return
+	}
+	ctx := requestContext(r)
+	if err := app.dataStore.DeleteLogin(ctx, cards.LoginID(cookie.Value)); err != nil {
+		app.logs.Error(ctx, "Failed to delete login, %v", err)
+	}
+}
+
+// endLogin logs the browser out, removing its login from the store and its cookie
+func (app *Application) endLogin(w http.ResponseWriter, r *http.Request) {
+	app.dropLogin(r)
+	http.SetCookie(w, &http.Cookie{
+		Name:     LOGIN_COOKIE,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// localTarget is where to go after logging in, which has to be a page on this site
+func localTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// register adds a new user, who needs an author key as an invite code, and logs them in
+func (app *Application) register(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	if r.Method != "POST" {
+		app.showTemplatePage("register", pageData{Title: "Register"}, w)
+		return
+	}
+
+	r.ParseForm()
+	name := cards.NormaliseUserName(r.Form.Get("name"))
+	password := r.Form.Get("password")
+	invite := strings.TrimSpace(r.Form.Get("invite"))
+
+	if err := cards.CheckUserName(name); err != nil {
+		app.logs.Info(ctx, "Refusing user name %q, %v", name, err)
+		app.showError(w, r, "1003")
+		return
+	}
+	if err := cards.CheckPassword(password); err != nil {
+		app.showError(w, r, "1004")
+		return
+	}
+	if !app.dataStore.IsValidAuthor(invite) {
+		app.showError(w, r, "3003")
+		return
+	}
+
+	user := cards.User{
+		Name:      name,
+		InvitedBy: "invite " + keyHash(invite),
+		Created:   time.Now().UTC(),
+	}
+	if err := user.SetPassword(password); err != nil {
+		app.logs.Error(ctx, "Failed to hash password, %v", err)
+		app.showError(w, r, "5002")
+		return
+	}
+	err := app.dataStore.PutUser(ctx, user)
+	if errors.Is(err, platform.ErrConflict) {
+		app.showError(w, r, "4003")
+		return
+	} else if err != nil {
+		app.showStoreError(w, r, err, "1001")
+		return
+	}
+	app.logs.Info(ctx, "Registered user %s", user.Name)
+
+	if app.startLogin(w, r, user.Name) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// login checks a user's password and logs them in, then returns them to the page they came from
+func (app *Application) login(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	if r.Method != "POST" {
+		data := pageData{
+			Title:   "Log in",
+			NextUrl: localTarget(r.FormValue("next")),
+		}
+		app.showTemplatePage("login", data, w)
+		return
+	}
+
+	r.ParseForm()
+	name := cards.NormaliseUserName(r.Form.Get("name"))
+	password := r.Form.Get("password")
+
+	// A name that could never have been registered is not looked up, as some stores cannot take it as an ID
+	if err := cards.CheckUserName(name); err != nil {
+		unknownUser().HasPassword(password)
+		app.logs.Info(ctx, "Login with invalid user name %q", name)
+		app.showError(w, r, "3004")
+		return
+	}
+
+	user, err := app.dataStore.GetUser(ctx, name)
+	if errors.Is(err, platform.ErrNotFound) {
+		unknownUser().HasPassword(password)
+		app.logs.Info(ctx, "Login for unknown user %q", name)
+		app.showError(w, r, "3004")
+		return
+	} else if err != nil {
+		app.showStoreError(w, r, err, "1001")
+		return
+	}
+	if !user.HasPassword(password) {
+		app.logs.Info(ctx, "Wrong password for user %s", name)
+		app.showError(w, r, "3004")
+		return
+	}
+
+	if app.startLogin(w, r, user.Name) {
+		http.Redirect(w, r, localTarget(r.Form.Get("next")), http.StatusSeeOther)
+	}
+}
+
+func (app *Application) logout(w http.ResponseWriter, r *http.Request) {
+	if user, ok := currentUser(r); ok {
+		app.logs.Info(requestContext(r), "Logging out user %s", user.Name)
+	}
+	app.endLogin(w, r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"flashcards/internal/platform"
+)
+
+// adminDecks browses every deck in the store, a page at a time. The listing is only shown to
+// users who have logged in.
+func (app *Application) adminDecks(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	data := pageData{
+		Title: "All decks",
+		Query: platform.DeckQuery{Sort: platform.SORT_BY_TITLE},
+		User:  pageUser(r),
+	}
+	if r.Method != "POST" {
+		app.showTemplatePage("admindecks", data, w)
+		return
+	}
+
+	r.ParseForm()
+	if data.User == nil {
+		app.showError(w, r, "3002")
+		return
+	}
+
+	data.Query = platform.DeckQuery{
+		Sort:       r.Form.Get("sort"),
+		Descending: r.Form.Get("descending") == "true",
+		Title:      r.Form.Get("title"),
+		TopLevel:   r.Form.Get("toplevel") == "true",
+		Cursor:     r.Form.Get("cursor"),
+	}
+	data.Query.Limit, _ = strconv.Atoi(r.Form.Get("limit"))
+	if err := data.Query.Check(); err != nil {
+		app.logs.Info(ctx, "Bad deck listing, %v", err)
+		app.showError(w, r, "1001")
+		return
+	}
+
+	page, err := app.dataStore.ListDecks(ctx, data.Query)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	data.DeckPage = &page
+	app.showTemplatePage("admindecks", data, w)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// actorID identifies who is making a change by their user name, as only logged-in users make changes
+func actorID(r *http.Request) string {
+	user, _ := currentUser(r)
+	return "user " + user.Name
+}
+
+// recordChange adds a change to its deck's history. The change itself has already been saved by
+// then, so a failure is logged rather than shown.
+func (app *Application) recordChange(r *http.Request, change cards.Change) {
+	ctx := requestContext(r)
+	if err := app.dataStore.PutChange(ctx, change); err != nil {
+		app.logs.Error(ctx, "Failed to record %s change to deck %s, %v", change.Action, change.DeckID, err)
+	}
+}
+
+// written is the card as a successful PutCard stores it
+func written(card cards.Card, deckID string) *cards.Card {
+	card.DeckID = deckID
+	card.Version++
+	return &card
+}
+
+// deckHistory lists every change to a deck and its cards, newest first
+func (app *Application) deckHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	deckID := mux.Vars(r)["id"]
+
+	deck, err := app.dataStore.GetDeck(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	changes, err := app.dataStore.GetChanges(ctx, deckID, "")
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	data := pageData{
+		Title:   deck.Title + " - History",
+		Deck:    deck,
+		Changes: changes,
+	}
+	app.showTemplatePage("changes", data, w)
+}
+
+// cardHistory lists every change to a card, newest first, and reverts the card to an earlier revision
+func (app *Application) cardHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	deckID := mux.Vars(r)["id"]
+	cardID := mux.Vars(r)["card"]
+
+	deck, err := app.dataStore.GetDeck(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	changes, err := app.dataStore.GetChanges(ctx, deckID, cardID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	if r.Method == "POST" {
+		if !app.requireUser(w, r) {
+			return
+		}
+		r.ParseForm()
+		changeID := r.Form.Get("change")
+		for _, change := range changes {
+			if change.ID == changeID && change.CanRevert() {
+				app.revertCard(w, r, *change.CardAfter)
+				return
+			}
+		}
+		app.showError(w, r, "2006")
+		return
+	}
+
+	card, ok := deck.Cards[cardID]
+	if !ok {
+		// A deleted card's history is still shown, with the card as it last was
+		if len(changes) == 0 || changes[0].CardBefore == nil {
+			app.showError(w, r, "2002")
+			return
+		}
+		card = *changes[0].CardBefore
+	}
+
+	data := pageData{
+		Title:   deck.Title + " - History",
+		Deck:    deck,
+		Card:    card,
+		Changes: changes,
+	}
+	app.showTemplatePage("changes", data, w)
+}
+
+// revertCard saves an earlier revision of a card over the current one, or puts it back if the card
+// has been deleted since
+func (app *Application) revertCard(w http.ResponseWriter, r *http.Request, revision cards.Card) {
+	ctx := requestContext(r)
+	deckID := mux.Vars(r)["id"]
+
+	var before *cards.Card
+	current, err := app.dataStore.GetCard(ctx, deckID, revision.ID)
+	if err == nil {
+		before = &current
+		revision.Version = current.Version
+	} else if !errors.Is(err, platform.ErrNotFound) {
+		app.showStoreError(w, r, err, "2002")
+		return
+	}
+
+	app.logs.Info(ctx, "Reverting card %s in deck %s", revision.ID, deckID)
+	if err := app.dataStore.PutCard(ctx, deckID, revision); err != nil {
+		app.showStoreError(w, r, err, "2002")
+		return
+	}
+	app.recordChange(r, cards.CardChange(cards.CHANGE_REVERTED, before, written(revision, deckID), actorID(r), time.Now()))
+
+	http.Redirect(w, r, "/deck/"+deckID+"/card/"+revision.ID+"/history", http.StatusSeeOther)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"flashcards/internal/cards"
+)
+
+// formVersion is the card or deck version that an edit form was showing, or the version just read
+// for forms that do not say, such as pages loaded before versions were added.
+func formVersion(r *http.Request, read int) int {
+	if !r.Form.Has("version") {
+		return read
+	}
+	version, err := strconv.Atoi(r.Form.Get("version"))
+	if err != nil {
+		return -1
+	}
+	return version
+}
+
+// showCardConflict shows the edit form again with the author's changes in it, next to the card as
+// someone else saved it, so that the author can decide whether to save theirs over it.
+func (app *Application) showCardConflict(w http.ResponseWriter, r *http.Request, edited cards.Card) {
+	ctx := requestContext(r)
+
+	deck, err := app.dataStore.GetDeck(ctx, edited.DeckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	current, ok := deck.Cards[edited.ID]
+	if !ok {
+		// There is nothing left to save over
+		app.showError(w, r, "2002")
+		return
+	}
+
+	app.logs.Info(ctx, "Conflicting edit of card %s in deck %s", edited.ID, deck.ID)
+	edited.Version = current.Version
+	data := pageData{
+		Deck:         deck,
+		Card:         edited,
+		FormAction:   "/editcard",
+		ConflictCard: &current,
+	}
+	app.showTemplatePageWithStatus("editcard", data, http.StatusConflict, w)
+}
+
+// showDeckConflict shows the deck edit form again with the author's changes in it, next to the deck
+// settings as someone else saved them.
+func (app *Application) showDeckConflict(w http.ResponseWriter, r *http.Request, edited cards.Deck) {
+	ctx := requestContext(r)
+
+	deck, err := app.dataStore.GetDeck(ctx, edited.ID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	app.logs.Info(ctx, "Conflicting edit of deck %s", deck.ID)
+	current := deck
+	edited.Version = deck.Version
+	data := pageData{
+		Title:        edited.Title,
+		Deck:         edited,
+		ConflictDeck: &current,
+	}
+	app.showTemplatePageWithStatus("editdeck", data, http.StatusConflict, w)
+}
@@ -1,19 +1,103 @@
 package handlers
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
 
-var errors = map[string]string{
+	"flashcards/internal/platform"
+)
+
+var errorMessages = map[string]string{
 	"1001": "Unknown error",
+	"1002": "Slugs need 3 to 40 lowercase letters and numbers, with single hyphens between words, and cannot look like a deck code",
+	"1003": "User names need 3 to 30 lowercase letters and numbers, with single dots, hyphens or underscores between them",
+	"1004": "Passwords need between 8 and 72 characters",
 	"2001": "Deck not found",
 	"2002": "Card not found",
-	"3001": "Not authorised to create new decks",
+	"2003": "Study session not found",
+	"2004": "Deck has sub-decks, move them to the trash first",
+	"2005": "Item not found in the trash",
+	"2006": "Revision not found in the card's history",
+	"3001": "Log in to create new decks",
+	"3002": "Log in to browse all decks",
+	"3003": "That invite code is not valid",
+	"3004": "Wrong user name or password",
+	"4001": "Someone else changed this at the same time, please try again",
+	"4002": "That slug is already used by another deck",
+	"4003": "That user name is already taken",
+	"5001": "The flashcard store is unavailable, please try again later",
+	"5002": "Something went wrong loading or saving flashcards",
+}
+
+// The HTTP status sent with each error page, anything not listed is an internal server error
+var errorStatuses = map[string]int{
+	"1001": http.StatusBadRequest,
+	"1002": http.StatusBadRequest,
+	"1003": http.StatusBadRequest,
+	"1004": http.StatusBadRequest,
+	"2001": http.StatusNotFound,
+	"2002": http.StatusNotFound,
+	"2003": http.StatusNotFound,
+	"2004": http.StatusConflict,
+	"2005": http.StatusNotFound,
+	"2006": http.StatusNotFound,
+	"3001": http.StatusForbidden,
+	"3002": http.StatusForbidden,
+	"3003": http.StatusForbidden,
+	"3004": http.StatusForbidden,
+	"4001": http.StatusConflict,
+	"4002": http.StatusConflict,
+	"4003": http.StatusConflict,
+	"5001": http.StatusServiceUnavailable,
 }
 
 func errorText(errorCode string) string {
-	text, ok := errors[errorCode]
+	text, ok := errorMessages[errorCode]
 	if ok {
 		return text
 	} else {
 		return fmt.Sprintf("Unknown error %s", errorCode)
 	}
 }
+
+func errorStatus(errorCode string) int {
+	status, ok := errorStatuses[errorCode]
+	if ok {
+		return status
+	} else {
+		return http.StatusInternalServerError
+	}
+}
+
+// showError responds with the error page for the error code, along with its HTTP status
+func (app *Application) showError(w http.ResponseWriter, r *http.Request, errorCode string) {
+	ctx := requestContext(r)
+	data := pageData{
+		Error: errorText(errorCode),
+	}
+	app.logs.Info(ctx, "Showing error page %s %s", errorCode, data.Error)
+	app.showTemplatePageWithStatus("error", data, errorStatus(errorCode), w)
+}
+
+// storeErrorCode picks the error code to show for a failed DataStore operation, using notFoundCode if the item does not exist
+func storeErrorCode(err error, notFoundCode string) string {
+	switch {
+	case errors.Is(err, platform.ErrNotFound):
+		return notFoundCode
+	case errors.Is(err, platform.ErrConflict):
+		return "4001"
+	case errors.Is(err, platform.ErrUnavailable):
+		return "5001"
+	default:
+		return "5002"
+	}
+}
+
+// showStoreError responds with the error page for a failed DataStore operation
+func (app *Application) showStoreError(w http.ResponseWriter, r *http.Request, err error, notFoundCode string) {
+	if !errors.Is(err, platform.ErrNotFound) {
+		app.logs.Error(requestContext(r), "Data store failure: %v", err)
+	}
+	app.showError(w, r, storeErrorCode(err, notFoundCode))
+}
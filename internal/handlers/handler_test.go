@@ -2,21 +2,25 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"flashcards/internal/cards"
 	"flashcards/internal/platform"
 	"flashcards/internal/test"
 )
 
-var p platform.Platform
+var app *Application
 
 func TestIndexPage(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 	defer wt.ShowBodyOnFail()
 
 	wt.SendGet("/")
@@ -27,9 +31,9 @@ func TestIndexPage(t *testing.T) {
 
 func TestShowDeckPage(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 	defer wt.ShowBodyOnFail()
 
 	wt.SendGet("/deck/TEST-CODE?share=true")
@@ -40,27 +44,25 @@ func TestShowDeckPage(t *testing.T) {
 }
 
 func setupPlatform() {
-	p = platform.LocalPlatform(context.Background())
-	logs = p.Logger()
-	dataStore = p.DataStore()
-	dataStore.Init(context.Background())
+	app = NewApplication(platform.NewLocalPlatform(context.Background()))
 }
 
 func TestDeckNotFound(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 	defer wt.ShowBodyOnFail()
 
 	wt.SendGet("/deck/BAD-CODE")
 
-	wt.AssertRedirectTo("/error?code=2001")
+	wt.AssertStatus(http.StatusNotFound)
+	wt.AssertBodyContains(".error", "Deck not found")
 }
 
 func TestErrorPage(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 	defer wt.ShowBodyOnFail()
 
 	wt.SendGet("/error?code=2002")
@@ -70,7 +72,7 @@ func TestErrorPage(t *testing.T) {
 
 func TestUnknownError(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 	defer wt.ShowBodyOnFail()
 
 	wt.SendGet("/error?code=9999")
@@ -80,7 +82,7 @@ func TestUnknownError(t *testing.T) {
 
 func TestDeckRedirect(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
 	wt.SendGet("/decks?deck=1234")
 
@@ -103,36 +105,91 @@ func TestDeckUrl(t *testing.T) {
 
 func TestNewDeck(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = logIn(t, "tester")
 
 	wt.SendPost("/newdeck", map[string]string{
-		"title":  "testing",
-		"author": "guessme",
+		"title": "testing",
 	})
 
 	wt.AssertRedirectToPrefix("/deck/")
+	deck, _ := app.dataStore.GetDeck(context.Background(), strings.TrimPrefix(wt.RedirectTarget(), "/deck/"))
+	if deck.CreatedBy != "tester" {
+		t.Errorf("Expected deck created by tester, got %q", deck.CreatedBy)
+	}
 }
 
-func TestNewDeckBadAuthor(t *testing.T) {
+func TestNewDeckNotLoggedIn(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
 	wt.SendPost("/newdeck", map[string]string{
 		"title":  "testing",
-		"author": "badkey",
+		"author": platform.TEST_AUTHOR_KEY,
 	})
 
-	wt.AssertRedirectTo("/error?code=3001")
+	wt.AssertStatus(http.StatusForbidden)
+	wt.AssertBodyContains(".error", "Log in to create new decks")
+}
+
+func TestChangesNeedLogin(t *testing.T) {
+	setupPlatform()
+	ctx := context.Background()
+	test.SetupTestData(ctx, app.dataStore, app.logs)
+	router := app.Router()
+	deck, _ := app.dataStore.GetDeck(ctx, "TEST-CODE")
+	card := deck.RandomCard()
+	item := cards.TrashCard(card, time.Now())
+	app.dataStore.PutTrashItem(ctx, item)
+
+	posts := []struct {
+		path   string
+		fields map[string]string
+	}{
+		{"/newcard", map[string]string{"deck_id": deck.ID, "question": "Q", "answer": "A"}},
+		{"/editcard", map[string]string{"deck_id": deck.ID, "card_id": card.ID, "question": "Changed"}},
+		{"/editdeck", map[string]string{"deck_id": deck.ID, "title": "Changed"}},
+		{"/deletecard", map[string]string{"deck_id": deck.ID, "card_id": card.ID}},
+		{"/deletedeck", map[string]string{"deck_id": deck.ID}},
+		{"/deck/" + deck.ID + "/trash", map[string]string{"item": item.ID, "action": "restore"}},
+		{"/deck/" + deck.ID + "/trash", map[string]string{"item": item.ID, "action": "purge"}},
+		{"/deck/" + deck.ID + "/card/" + card.ID + "/history", map[string]string{"change": "C1"}},
+	}
+	for _, post := range posts {
+		wt := test.NewWebTest(t, *router)
+		wt.SendPost(post.path, post.fields)
+		wt.AssertStatus(http.StatusForbidden)
+	}
+
+	after, _ := app.dataStore.GetDeck(ctx, deck.ID)
+	if after.Version != deck.Version || len(after.Cards) != len(deck.Cards) || after.GetCard(card.ID).Question != card.Question {
+		t.Errorf("Deck changed without a login: %+v", after)
+	}
+	if _, err := app.dataStore.GetTrashItem(ctx, item.ID); err != nil {
+		t.Errorf("Trash item changed without a login: %v", err)
+	}
+}
+
+// logIn adds a user along with a login straight to the store, returning the cookie that logs them in
+func logIn(t *testing.T, name string) []*http.Cookie {
+	ctx := context.Background()
+	app.dataStore.PutUser(ctx, cards.User{Name: name})
+	login, token, err := cards.NewLogin(name, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to start login: %v", err)
+	}
+	app.dataStore.PutLogin(ctx, login)
+	return []*http.Cookie{{Name: LOGIN_COOKIE, Value: token}}
 }
 
 func TestCardPage(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
-	deck := dataStore.GetDeck(context.Background(), "TEST-CODE")
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
 	card := deck.RandomCard()
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 	defer wt.ShowBodyOnFail()
 
 	wt.SendGet("/deck/" + card.DeckID + "/card/" + card.ID)
@@ -144,33 +201,34 @@ func TestCardPage(t *testing.T) {
 
 func TestCardNotFound(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
-	// Need to initialise datastore after creating the ApplicationRouter for the test
-	dataStore = &platform.TestDataStore{}
-	dataStore.PutDeck(context.Background(), "123", cards.Deck{ID: "123"})
+	app.dataStore = &platform.TestDataStore{}
+	app.dataStore.PutDeck(context.Background(), "123", cards.Deck{ID: "123"})
 
 	wt.SendGet("/deck/123/card/789")
 
-	wt.AssertRedirectTo("/error?code=2002")
+	wt.AssertStatus(http.StatusNotFound)
+	wt.AssertBodyContains(".error", "Card not found")
 }
 
 func TestCardDeckNotFound(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
-	dataStore = &platform.TestDataStore{}
+	app.dataStore = &platform.TestDataStore{}
 
 	wt.SendGet("/deck/234/card/789")
 
-	wt.AssertRedirectTo("/error?code=2001")
+	wt.AssertStatus(http.StatusNotFound)
+	wt.AssertBodyContains(".error", "Deck not found")
 }
 
 func TestRandomCardPage(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
 	wt.SendGet("/random?deck=TEST-CODE")
 
@@ -179,8 +237,8 @@ func TestRandomCardPage(t *testing.T) {
 
 func TestRandomCardBadDeck(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
-	test.SetupTestData(context.Background(), dataStore, logs)
+	wt := test.NewWebTest(t, *app.Router())
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
 	wt.SendGet("/random?deck=BAD-CODE")
 
@@ -189,12 +247,12 @@ func TestRandomCardBadDeck(t *testing.T) {
 
 func TestEditCardForm(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
-	deck := dataStore.GetDeck(context.Background(), "TEST-CODE")
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
 	cardID := deck.RandomCard().ID
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
 	wt.SendGet("/editcard?deck=TEST-CODE&card=" + cardID)
 
@@ -203,14 +261,15 @@ func TestEditCardForm(t *testing.T) {
 
 func TestPostEditCard(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
 	deckID := "TEST-CODE"
-	deck := dataStore.GetDeck(context.Background(), deckID)
+	deck, _ := app.dataStore.GetDeck(context.Background(), deckID)
 	cardID := deck.RandomCard().ID
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
+	wt.Cookies = logIn(t, "tester")
 	wt.SendPost("/editcard", map[string]string{
 		"deck_id":  deckID,
 		"card_id":  cardID,
@@ -221,7 +280,7 @@ func TestPostEditCard(t *testing.T) {
 
 	wt.AssertRedirectTo("/deck/" + deckID + "/card/" + cardID + "?answer=show")
 
-	deck = dataStore.GetDeck(context.Background(), deckID)
+	deck, _ = app.dataStore.GetDeck(context.Background(), deckID)
 	card := deck.Cards[cardID]
 
 	if card.Question != "NewQ" {
@@ -229,11 +288,63 @@ func TestPostEditCard(t *testing.T) {
 	}
 }
 
+func TestPostEditCardConflict(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Deck"}
+	deck.AddCard(cards.Card{ID: "A", Question: "Original"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+	read, _ := app.dataStore.GetCard(context.Background(), "123", "A")
+
+	// Someone else saves the card after the edit form was shown
+	other := read
+	other.Question = "Theirs"
+	app.dataStore.PutCard(context.Background(), "123", other)
+
+	wt := test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editcard", map[string]string{
+		"deck_id":  "123",
+		"card_id":  "A",
+		"version":  fmt.Sprint(read.Version),
+		"question": "Mine",
+	})
+
+	wt.AssertStatus(http.StatusConflict)
+	wt.AssertBodyContains("#conflict .question", "Theirs")
+	wt.AssertBodyContains("#question", "Mine")
+
+	saved, _ := app.dataStore.GetCard(context.Background(), "123", "A")
+	if saved.Question != "Theirs" {
+		t.Errorf("Conflicting edit was saved: %s", saved.Question)
+	}
+
+	// Saving again from the conflict page replaces their change
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editcard", map[string]string{
+		"deck_id":  "123",
+		"card_id":  "A",
+		"version":  fmt.Sprint(saved.Version),
+		"question": "Mine",
+	})
+	wt.AssertRedirectTo("/deck/123/card/A?answer=show")
+
+	saved, _ = app.dataStore.GetCard(context.Background(), "123", "A")
+	if saved.Question != "Mine" {
+		t.Errorf("Re-applied edit not saved: %s", saved.Question)
+	}
+}
+
 func TestAddCardForm(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
 	wt.SendGet("/newcard?deck=TEST-CODE")
 
@@ -242,11 +353,12 @@ func TestAddCardForm(t *testing.T) {
 
 func TestPostAddCard(t *testing.T) {
 	setupPlatform()
-	test.SetupTestData(context.Background(), dataStore, logs)
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
 
 	deckID := "TEST-CODE"
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
+	wt.Cookies = logIn(t, "tester")
 	wt.SendPost("/newcard", map[string]string{
 		"deck_id":  deckID,
 		"question": "NewQ",
@@ -259,7 +371,7 @@ func TestPostAddCard(t *testing.T) {
 
 func TestQrCodes(t *testing.T) {
 	setupPlatform()
-	wt := test.NewWebTest(t, *ApplicationRouter(p))
+	wt := test.NewWebTest(t, *app.Router())
 
 	wt.SendGet("/qrcode?deck=TEST-CODE")
 
@@ -268,10 +380,10 @@ func TestQrCodes(t *testing.T) {
 
 func TestTemplateNotFound(t *testing.T) {
 	lr := test.LogRecorder{}
-	logs = &lr
+	app := &Application{logs: &lr}
 	w := httptest.NewRecorder()
 
-	showTemplatePage("notfound", nil, w)
+	app.showTemplatePage("notfound", nil, w)
 
 	if w.Result().StatusCode != 500 {
 		t.Errorf("Unexpected http response: %d", w.Result().StatusCode)
@@ -283,10 +395,10 @@ func TestTemplateNotFound(t *testing.T) {
 
 func TestTemplateExecutionError(t *testing.T) {
 	lr := test.LogRecorder{}
-	logs = &lr
+	app := &Application{logs: &lr}
 	w := httptest.NewRecorder()
 
-	showTemplatePage("deck", "broken", w)
+	app.showTemplatePage("deck", "broken", w)
 
 	if !strings.Contains(w.Body.String(), "Internal Server Error") {
 		t.Errorf("Unexpected http response: %d", w.Result().StatusCode)
@@ -296,3 +408,1187 @@ func TestTemplateExecutionError(t *testing.T) {
 		t.Error("Expected log entry not found")
 	}
 }
+
+func TestReviewCard(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deckID := "TEST-CODE"
+	deck, _ := app.dataStore.GetDeck(context.Background(), deckID)
+	cardID := deck.RandomCard().ID
+
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.SendPost("/review", map[string]string{
+		"deck_id": deckID,
+		"card_id": cardID,
+		"grade":   "2",
+	})
+
+	wt.AssertRedirectTo("/random?deck=" + deckID)
+
+	learner := wt.Response.Result().Cookies()[0].Value
+	progress, _ := app.dataStore.GetProgress(context.Background(), learner, deckID)
+	if progress.Reviews[cardID].Interval != 1 {
+		t.Errorf("Unexpected review after grading: %+v", progress.Reviews[cardID])
+	}
+}
+
+func TestReviewWithBadLearnerCookie(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deckID := "TEST-CODE"
+	deck, _ := app.dataStore.GetDeck(context.Background(), deckID)
+	cardID := deck.RandomCard().ID
+
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = []*http.Cookie{{Name: LEARNER_COOKIE, Value: "../0123456789ABCDEF"}}
+	wt.SendPost("/review", map[string]string{
+		"deck_id": deckID,
+		"card_id": cardID,
+		"grade":   "2",
+	})
+
+	wt.AssertRedirectTo("/random?deck=" + deckID)
+	cookie := responseCookie(wt, LEARNER_COOKIE)
+	if cookie == nil || !learnerIDPattern.MatchString(cookie.Value) {
+		t.Fatalf("Expected a new learner ID, got %+v", cookie)
+	}
+	progress, _ := app.dataStore.GetProgress(context.Background(), cookie.Value, deckID)
+	if _, ok := progress.Reviews[cardID]; !ok {
+		t.Errorf("Review not recorded against the new learner ID: %+v", progress)
+	}
+}
+
+func TestReviewCardBadGrade(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.SendPost("/review", map[string]string{
+		"deck_id": "TEST-CODE",
+		"card_id": "1234",
+		"grade":   "9",
+	})
+
+	wt.AssertStatus(http.StatusBadRequest)
+	wt.AssertBodyContains(".error", "Unknown error")
+}
+
+func TestReviewUnknownCard(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+	router := app.Router()
+
+	for _, fields := range []map[string]string{
+		{"deck_id": "TEST-CODE", "card_id": "NOT-A-CARD", "grade": "2"},
+		{"deck_id": "BAD-CODE", "card_id": "NOT-A-CARD", "grade": "2"},
+	} {
+		wt := test.NewWebTest(t, *router)
+		wt.Cookies = []*http.Cookie{{Name: LEARNER_COOKIE, Value: "0123456789ABCDEF"}}
+		wt.SendPost("/review", fields)
+		wt.AssertStatus(http.StatusNotFound)
+
+		progress, _ := app.dataStore.GetProgress(context.Background(), "0123456789ABCDEF", fields["deck_id"])
+		if len(progress.Reviews) != 0 {
+			t.Errorf("Progress recorded for a card that does not exist: %+v", progress.Reviews)
+		}
+	}
+}
+
+func TestLeitnerCardPage(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.SendGet("/leitner?deck=TEST-CODE")
+
+	wt.AssertRedirectToPrefix("/deck/TEST-CODE/card")
+	if !strings.HasSuffix(wt.RedirectTarget(), "mode=leitner") {
+		t.Errorf("Leitner mode not passed to card page: %s", wt.RedirectTarget())
+	}
+}
+
+func TestPostLeitnerResult(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deckID := "TEST-CODE"
+	deck, _ := app.dataStore.GetDeck(context.Background(), deckID)
+	cardID := deck.RandomCard().ID
+
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.SendPost("/leitner", map[string]string{
+		"deck_id": deckID,
+		"card_id": cardID,
+		"result":  "right",
+	})
+
+	wt.AssertRedirectTo("/leitner?deck=" + deckID)
+
+	learner := wt.Response.Result().Cookies()[0].Value
+	progress, _ := app.dataStore.GetProgress(context.Background(), learner, deckID)
+	if progress.Box(cardID) != 2 {
+		t.Errorf("Card not promoted to box 2: %d", progress.Box(cardID))
+	}
+}
+
+func TestPostLeitnerUnknownCard(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = []*http.Cookie{{Name: LEARNER_COOKIE, Value: "0123456789ABCDEF"}}
+	wt.SendPost("/leitner", map[string]string{
+		"deck_id": "TEST-CODE",
+		"card_id": "NOT-A-CARD",
+		"result":  "right",
+	})
+	wt.AssertStatus(http.StatusNotFound)
+
+	progress, _ := app.dataStore.GetProgress(context.Background(), "0123456789ABCDEF", "TEST-CODE")
+	if len(progress.Reviews) != 0 {
+		t.Errorf("Leitner box recorded for a card that does not exist: %+v", progress.Reviews)
+	}
+}
+
+func TestDeckPageShowsBoxes(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	wt := test.NewWebTest(t, *app.Router())
+	defer wt.ShowBodyOnFail()
+
+	wt.SendGet("/deck/TEST-CODE")
+
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#boxes", "Box 5")
+}
+
+func TestStudySession(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+	router := app.Router()
+
+	wt := test.NewWebTest(t, *router)
+	wt.SendGet("/session?deck=TEST-CODE")
+	wt.AssertRedirectToPrefix("/session/")
+	sessionUrl := wt.RedirectTarget()
+
+	session, _ := app.dataStore.GetSession(context.Background(), strings.TrimPrefix(sessionUrl, "/session/"))
+	if len(session.Order) != 5 {
+		t.Fatalf("Unexpected number of cards in session: %d", len(session.Order))
+	}
+
+	for i := 0; i < len(session.Order); i++ {
+		wt = test.NewWebTest(t, *router)
+		wt.SendGet(sessionUrl)
+		wt.AssertRedirectToPrefix("/deck/TEST-CODE/card/")
+
+		session, _ = app.dataStore.GetSession(context.Background(), session.ID)
+		wt = test.NewWebTest(t, *router)
+		wt.SendPost(sessionUrl, map[string]string{
+			"card_id": session.Current(),
+			"result":  "wrong",
+		})
+	}
+
+	wt.AssertRedirectTo(sessionUrl + "/results")
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet(sessionUrl + "/results")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#summary", "5 wrong")
+	wt.AssertBodyContains("#retry", "Retry the missed cards")
+}
+
+func TestSessionNotFound(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.SendGet("/session/NOSUCHSESSION")
+
+	wt.AssertStatus(http.StatusNotFound)
+	wt.AssertBodyContains(".error", "Study session not found")
+}
+
+func TestCardPageInSession(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	session := cards.NewSession(deck.ID, deck.Prompts())
+	app.dataStore.PutSession(context.Background(), session)
+
+	wt := test.NewWebTest(t, *app.Router())
+	defer wt.ShowBodyOnFail()
+
+	wt.SendGet("/deck/TEST-CODE/card/" + session.Current() + "?answer=show&hinted=true&mode=session&session=" + session.ID)
+
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#session_progress", "Card 1 of 5")
+	wt.AssertBodyContains("#session", "I was right")
+}
+
+func TestTypedAnswerCheck(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	var cardID string
+	for id, card := range deck.Cards {
+		if card.Answer == "42" {
+			cardID = id
+		}
+	}
+
+	wt := test.NewWebTest(t, *app.Router())
+	defer wt.ShowBodyOnFail()
+
+	wt.SendPost("/deck/TEST-CODE/card/"+cardID+"/check", map[string]string{
+		"typed": "41",
+	})
+
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#check", "Not quite.")
+	wt.AssertBodyContains("#check ins", "2")
+}
+
+func TestPostEditCardAlternatives(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deckID := "TEST-CODE"
+	deck, _ := app.dataStore.GetDeck(context.Background(), deckID)
+	cardID := deck.RandomCard().ID
+
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editcard", map[string]string{
+		"deck_id":      deckID,
+		"card_id":      cardID,
+		"question":     "NewQ",
+		"answer":       "NewA",
+		"alternatives": "Alt1%0A%0AAlt2%0A",
+	})
+
+	deck, _ = app.dataStore.GetDeck(context.Background(), deckID)
+	card := deck.Cards[cardID]
+
+	if len(card.Alternatives) != 2 || card.Alternatives[1] != "Alt2" {
+		t.Errorf("Unexpected alternatives after edit: %v", card.Alternatives)
+	}
+}
+
+func TestClozeCardPage(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+	defer wt.ShowBodyOnFail()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Cloze"}
+	deck.AddCard(cards.Card{ID: "C", Type: cards.CLOZE_CARD, Question: "{{c1::Paris}} is the capital of {{c2::France}}"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt.SendGet("/deck/123/card/C?answer=show&cloze=2")
+
+	wt.AssertSuccess()
+	wt.AssertBodyContains("body", "Paris is the capital of [...]")
+	wt.AssertBodyContains("strong", "France")
+}
+
+func TestRandomClozePrompt(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Cloze"}
+	deck.AddCard(cards.Card{ID: "C", Type: cards.CLOZE_CARD, Question: "{{c1::Paris}} is the capital of {{c2::France}}"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt.SendGet("/random?deck=123")
+
+	wt.AssertRedirectToPrefix("/deck/123/card/C?answer=hide&cloze=")
+}
+
+func TestMultipleChoiceCard(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Choices"}
+	deck.AddCard(cards.Card{ID: "M", Type: cards.CHOICE_CARD, Question: "2+2", Answer: "4"})
+	deck.AddCard(cards.Card{ID: "N", Question: "3+3", Answer: "6"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt := test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/123/card/M?answer=hide")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#choices", "6")
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendPost("/deck/123/card/M/choose?mode=leitner", map[string]string{
+		"choice": "4",
+	})
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#chosen", "Correct!")
+
+	learner := wt.Response.Result().Cookies()[0].Value
+	progress, _ := app.dataStore.GetProgress(context.Background(), learner, "123")
+	if progress.Box("M") != 2 {
+		t.Errorf("Correct choice not recorded in Leitner box: %d", progress.Box("M"))
+	}
+
+	// Following a link, as a prefetch or crawler would, records nothing
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = []*http.Cookie{{Name: LEARNER_COOKIE, Value: learner}}
+	wt.SendGet("/deck/123/card/M/choose?mode=leitner&choice=4")
+	wt.AssertStatus(http.StatusMethodNotAllowed)
+	progress, _ = app.dataStore.GetProgress(context.Background(), learner, "123")
+	if progress.Box("M") != 2 {
+		t.Errorf("Choice sent by GET was recorded: %d", progress.Box("M"))
+	}
+}
+
+func TestRandomReversedPrompt(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Vocab", Reversible: true}
+	deck.AddCard(cards.Card{ID: "V", Question: "chat", Answer: "cat"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt.SendGet("/random?deck=123&direction=reverse")
+
+	wt.AssertRedirectTo("/deck/123/card/V?answer=hide&direction=reverse&reversed=true")
+}
+
+func TestReversedCardPage(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+	defer wt.ShowBodyOnFail()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Vocab", Reversible: true}
+	deck.AddCard(cards.Card{ID: "V", Question: "chat", Answer: "cat"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt.SendGet("/deck/123/card/V?answer=hide&direction=reverse")
+
+	wt.AssertSuccess()
+	wt.AssertBodyContains("body", "What was the question?")
+	wt.AssertBodyContains("p", "cat")
+}
+
+func TestPostEditDeck(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editdeck", map[string]string{
+		"deck_id":    "TEST-CODE",
+		"title":      "Renamed",
+		"reversible": "true",
+	})
+
+	wt.AssertRedirectTo("/deck/TEST-CODE")
+
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	if deck.Title != "Renamed" || !deck.Reversible {
+		t.Errorf("Deck not updated: %s %v", deck.Title, deck.Reversible)
+	}
+}
+
+func TestPostEditDeckConflict(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	read, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	other := read
+	other.Title = "Their title"
+	app.dataStore.PutDeck(context.Background(), "TEST-CODE", other)
+
+	wt := test.NewWebTest(t, *app.Router())
+	defer wt.ShowBodyOnFail()
+
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editdeck", map[string]string{
+		"deck_id": "TEST-CODE",
+		"version": fmt.Sprint(read.Version),
+		"title":   "My title",
+	})
+
+	wt.AssertStatus(http.StatusConflict)
+	wt.AssertBodyContains("#conflict .title", "Their title")
+
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	if deck.Title != "Their title" {
+		t.Errorf("Conflicting edit was saved: %s", deck.Title)
+	}
+}
+
+func TestPostEditDeckKeepsNewCards(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	// A card is added while the deck edit form is open
+	read, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	app.dataStore.PutCard(context.Background(), "TEST-CODE", cards.Card{ID: "NEWCARD", Question: "Added"})
+
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editdeck", map[string]string{
+		"deck_id": "TEST-CODE",
+		"version": fmt.Sprint(read.Version),
+		"title":   "Renamed",
+	})
+	wt.AssertRedirectTo("/deck/TEST-CODE")
+
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	if deck.Title != "Renamed" || deck.Cards["NEWCARD"].Question != "Added" {
+		t.Errorf("Unexpected deck after edit %s with %d cards", deck.Title, len(deck.Cards))
+	}
+}
+
+func TestTagFilteredStudy(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Tagged"}
+	deck.AddCard(cards.Card{ID: "A", Question: "QA", Tags: []string{"verbs"}})
+	deck.AddCard(cards.Card{ID: "B", Question: "QB", Tags: []string{"nouns"}})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt := test.NewWebTest(t, *router)
+	wt.SendGet("/random?deck=123&tag=verbs")
+	wt.AssertRedirectTo("/deck/123/card/A?answer=hide&tag=verbs")
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/123?tag=nouns")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#tags", "verbs (1)")
+	wt.AssertBodyContains("#tagfilter", "nouns")
+	wt.AssertBodyContains("ul", "QB")
+}
+
+func TestPostEditCardTags(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+
+	deckID := "TEST-CODE"
+	deck, _ := app.dataStore.GetDeck(context.Background(), deckID)
+	cardID := deck.RandomCard().ID
+
+	wt := test.NewWebTest(t, *app.Router())
+
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editcard", map[string]string{
+		"deck_id":  deckID,
+		"card_id":  cardID,
+		"question": "NewQ",
+		"tags":     "Alpha,%20beta",
+	})
+
+	deck, _ = app.dataStore.GetDeck(context.Background(), deckID)
+	card := deck.Cards[cardID]
+	if len(card.Tags) != 2 || card.Tags[1] != "beta" {
+		t.Errorf("Unexpected tags after edit: %v", card.Tags)
+	}
+}
+
+func TestSubDecks(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	parent := cards.Deck{ID: "PARENT", Title: "Languages"}
+	app.dataStore.PutDeck(context.Background(), parent.ID, parent)
+	child := cards.Deck{ID: "CHILD", Title: "French", ParentID: "PARENT"}
+	child.AddCard(cards.Card{ID: "A", Question: "Bonjour", Answer: "Hello"})
+	app.dataStore.PutDeck(context.Background(), child.ID, child)
+
+	wt := test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/PARENT")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#subdecks a", "French")
+	wt.AssertBodyContains("#subdecks .count", "(1 cards)")
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendGet("/deck/CHILD")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#parent", "Languages")
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendGet("/random?deck=PARENT")
+	wt.AssertRedirectTo("/deck/PARENT/card/A?answer=hide")
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendGet("/deck/PARENT/card/A?answer=hide")
+	wt.AssertSuccess()
+}
+
+func TestNewSubDeck(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = logIn(t, "tester")
+
+	wt.SendPost("/newdeck", map[string]string{
+		"title":  "child",
+		"parent": "TEST-CODE",
+	})
+
+	wt.AssertRedirectToPrefix("/deck/")
+	children, _ := app.dataStore.GetChildDecks(context.Background(), "TEST-CODE")
+	if len(children) != 1 || children[0].Title != "child" {
+		t.Errorf("Expected one sub-deck, got %v", children)
+	}
+}
+
+func TestNewSubDeckBadParent(t *testing.T) {
+	setupPlatform()
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = logIn(t, "tester")
+
+	wt.SendPost("/newdeck", map[string]string{
+		"title":  "child",
+		"parent": "BAD-CODE",
+	})
+
+	wt.AssertStatus(http.StatusNotFound)
+	wt.AssertBodyContains(".error", "Deck not found")
+}
+
+func TestDeleteAndRestoreCard(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Trashy"}
+	deck.AddCard(cards.Card{ID: "A", Question: "QA"})
+	deck.AddCard(cards.Card{ID: "B", Question: "QB"})
+	app.dataStore.PutDeck(context.Background(), "123", deck)
+
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deletecard", map[string]string{"deck_id": "123", "card_id": "A"})
+	wt.AssertRedirectTo("/deck/123")
+
+	deck, _ = app.dataStore.GetDeck(context.Background(), "123")
+	if _, ok := deck.Cards["A"]; ok {
+		t.Error("Card still in deck after delete")
+	}
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/123/trash")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#trash .title", "QA")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deck/123/trash", map[string]string{"item": "A", "action": "restore"})
+	wt.AssertRedirectTo("/deck/123")
+
+	deck, _ = app.dataStore.GetDeck(context.Background(), "123")
+	if _, ok := deck.Cards["A"]; !ok {
+		t.Error("Card not back in deck after restore")
+	}
+	if trash, _ := app.dataStore.GetTrash(context.Background(), "123"); len(trash) != 0 {
+		t.Error("Trash not empty after restore")
+	}
+}
+
+func TestDeleteAndPurgeDeck(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	app.dataStore.PutDeck(context.Background(), "123", cards.Deck{ID: "123", Title: "Doomed"})
+
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deletedeck", map[string]string{"deck_id": "123"})
+	wt.AssertRedirectTo("/deck/123/trash")
+
+	if _, err := app.dataStore.GetDeck(context.Background(), "123"); !errors.Is(err, platform.ErrNotFound) {
+		t.Error("Deck still exists after delete")
+	}
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/123/trash")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#message", "in the trash")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deck/123/trash", map[string]string{"item": "123", "action": "purge"})
+	wt.AssertRedirectTo("/deck/123/trash")
+
+	if _, err := app.dataStore.GetTrashItem(context.Background(), "123"); err == nil {
+		t.Error("Deck still in trash after purge")
+	}
+}
+
+func TestDeleteDeckWithSubDecks(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	app.dataStore.PutDeck(context.Background(), "PARENT", cards.Deck{ID: "PARENT"})
+	app.dataStore.PutDeck(context.Background(), "CHILD", cards.Deck{ID: "CHILD", ParentID: "PARENT"})
+
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deletedeck", map[string]string{"deck_id": "PARENT"})
+	wt.AssertStatus(http.StatusConflict)
+	wt.AssertBodyContains(".error", "sub-decks")
+}
+
+func TestExpiredTrashIsPurged(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	app.dataStore = &platform.TestDataStore{}
+	app.dataStore.PutDeck(context.Background(), "123", cards.Deck{ID: "123"})
+	app.dataStore.PutTrashItem(context.Background(), cards.TrashCard(cards.Card{ID: "A", DeckID: "123"}, time.Now().Add(-cards.TRASH_RETENTION)))
+
+	wt := test.NewWebTest(t, *router)
+	wt.SendGet("/deck/123/trash")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#empty", "empty")
+
+	if _, err := app.dataStore.GetTrashItem(context.Background(), "A"); err == nil {
+		t.Error("Expired item still in trash")
+	}
+}
+
+// failingDataStore is an in-memory store whose writes fail with the given error
+type failingDataStore struct {
+	platform.TestDataStore
+	err error
+}
+
+func (store *failingDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	return store.err
+}
+
+func (store *failingDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	return store.err
+}
+
+func TestPostEditCardStoreFailure(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+
+	store := &failingDataStore{err: platform.ErrUnavailable}
+	deck := cards.Deck{ID: "123", Title: "Unsaved"}
+	deck.AddCard(cards.Card{ID: "A", Question: "QA"})
+	store.TestDataStore.PutDeck(context.Background(), "123", deck)
+	app.dataStore = store
+
+	wt := test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editcard", map[string]string{
+		"deck_id":  "123",
+		"card_id":  "A",
+		"question": "NewQ",
+	})
+	wt.AssertStatus(http.StatusServiceUnavailable)
+	wt.AssertBodyContains(".error", "unavailable")
+
+	store.err = fmt.Errorf("write rejected: %w", platform.ErrConflict)
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editdeck", map[string]string{"deck_id": "123", "title": "New"})
+	wt.AssertStatus(http.StatusConflict)
+}
+
+func TestCardHistoryAndRevert(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+
+	app.dataStore = &platform.TestDataStore{}
+	deck := cards.Deck{ID: "123", Title: "Changing"}
+	deck.AddCard(cards.Card{ID: "A", Question: "Q1", Answer: "A1"})
+	app.dataStore.PutDeck(ctx, "123", deck)
+
+	for _, question := range []string{"Q2", "Q3"} {
+		wt := test.NewWebTest(t, *router)
+		wt.Cookies = logIn(t, "tester")
+		wt.SendPost("/editcard", map[string]string{"deck_id": "123", "card_id": "A", "question": question, "answer": "A1"})
+		wt.AssertRedirectTo("/deck/123/card/A?answer=show")
+		time.Sleep(time.Millisecond) // so that the changes sort in order
+	}
+
+	changes, _ := app.dataStore.GetChanges(ctx, "123", "A")
+	if len(changes) != 2 || changes[0].CardAfter.Question != "Q3" || changes[1].CardBefore.Question != "Q1" {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+	if changes[0].Actor != "user tester" {
+		t.Errorf("Unexpected actor %s", changes[0].Actor)
+	}
+
+	wt := test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/123/card/A/history")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#changes .after", "Q3")
+	wt.AssertBodyContains("#changes .before", "Q1")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deck/123/card/A/history", map[string]string{"change": changes[1].ID})
+	wt.AssertRedirectTo("/deck/123/card/A/history")
+
+	card, _ := app.dataStore.GetCard(ctx, "123", "A")
+	if card.Question != "Q2" || card.Version != 4 {
+		t.Errorf("Card not reverted: %+v", card)
+	}
+	changes, _ = app.dataStore.GetChanges(ctx, "123", "")
+	if len(changes) != 3 || changes[0].Action != cards.CHANGE_REVERTED || changes[0].CardBefore.Question != "Q3" {
+		t.Errorf("Revert not recorded: %+v", changes)
+	}
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deck/123/card/A/history", map[string]string{"change": "MISSING"})
+	wt.AssertStatus(http.StatusNotFound)
+}
+
+func TestDeletedCardHistory(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+
+	app.dataStore = &platform.TestDataStore{}
+	app.dataStore.PutDeck(ctx, "123", cards.Deck{ID: "123", Title: "Changing"})
+
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/newcard", map[string]string{"deck_id": "123", "question": "Gone"})
+	wt.AssertRedirectTo("/deck/123")
+	changes, _ := app.dataStore.GetChanges(ctx, "123", "")
+	if len(changes) != 1 || changes[0].Action != cards.CHANGE_CREATED {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+	cardID := changes[0].CardID
+
+	time.Sleep(time.Millisecond)
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deletecard", map[string]string{"deck_id": "123", "card_id": cardID})
+	wt.AssertRedirectTo("/deck/123")
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/123/history")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#changes .action", "deleted")
+	wt.AssertBodyContains("#changes .title", "Gone")
+
+	// The deleted card's history can still be seen, and it can be put back from there
+	wt = test.NewWebTest(t, *router)
+	wt.SendGet("/deck/123/card/" + cardID + "/history")
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#card", "Gone")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/deck/123/card/"+cardID+"/history", map[string]string{"change": changes[0].ID})
+	wt.AssertRedirectTo("/deck/123/card/" + cardID + "/history")
+	if card, err := app.dataStore.GetCard(ctx, "123", cardID); err != nil || card.Question != "Gone" {
+		t.Errorf("Deleted card not put back: %+v, %v", card, err)
+	}
+}
+
+func TestDeckChangesRecorded(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+
+	login := logIn(t, "tester")
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "Recorded"})
+	deckID := strings.TrimPrefix(wt.RedirectTarget(), "/deck/")
+
+	time.Sleep(time.Millisecond)
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "editor")
+	wt.SendPost("/editdeck", map[string]string{"deck_id": deckID, "title": "Renamed"})
+	wt.AssertRedirectTo("/deck/" + deckID)
+
+	changes, _ := app.dataStore.GetChanges(ctx, deckID, "")
+	if len(changes) != 2 || !changes[1].IsDeck() || changes[1].Action != cards.CHANGE_CREATED {
+		t.Fatalf("Unexpected changes %+v", changes)
+	}
+	if changes[1].Actor != "user tester" || changes[0].Actor != "user editor" {
+		t.Errorf("Unexpected actors %s and %s", changes[1].Actor, changes[0].Actor)
+	}
+	if changes[0].DeckBefore.Title != "Recorded" || changes[0].DeckAfter.Title != "Renamed" {
+		t.Errorf("Unexpected edit %+v", changes[0])
+	}
+}
+
+func TestAdminDeckBrowser(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+
+	app.dataStore = &platform.TestDataStore{}
+	for i, title := range []string{"Alpha", "Beta", "Gamma"} {
+		deck := cards.Deck{ID: fmt.Sprintf("D%d", i), Title: title}
+		for c := 0; c <= i; c++ {
+			deck.AddCard(cards.Card{Question: "Q"})
+		}
+		app.dataStore.PutDeck(ctx, deck.ID, deck)
+	}
+
+	wt := test.NewWebTest(t, *router)
+	wt.SendGet("/admin/decks")
+	wt.AssertSuccess()
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendPost("/admin/decks", map[string]string{"author": platform.TEST_AUTHOR_KEY})
+	wt.AssertStatus(http.StatusForbidden)
+
+	login := logIn(t, "tester")
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	defer wt.ShowBodyOnFail()
+	wt.SendPost("/admin/decks", map[string]string{
+		"sort":       "cards",
+		"descending": "true",
+		"limit":      "2",
+	})
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#decks .title", "Gamma")
+	wt.AssertBodyContains("#decks .count", "3")
+
+	page, _ := app.dataStore.ListDecks(ctx, platform.DeckQuery{Sort: "cards", Descending: true, Limit: 2})
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/admin/decks", map[string]string{
+		"sort":       "cards",
+		"descending": "true",
+		"limit":      "2",
+		"cursor":     page.NextCursor,
+	})
+	wt.AssertSuccess()
+	wt.AssertBodyContains("#decks .title", "Alpha")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/admin/decks", map[string]string{"sort": "colour"})
+	wt.AssertStatus(http.StatusBadRequest)
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/admin/decks?cursor=garbage", nil)
+	wt.AssertStatus(http.StatusBadRequest)
+}
+
+func TestIsolatedApplications(t *testing.T) {
+	ctx := context.Background()
+	first := NewApplication(platform.NewLocalPlatform(ctx))
+	second := NewApplication(platform.NewLocalPlatform(ctx))
+	test.SetupTestData(ctx, first.dataStore, first.logs)
+
+	wt := test.NewWebTest(t, *first.Router())
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/TEST-CODE")
+	wt.AssertSuccess()
+
+	wt = test.NewWebTest(t, *second.Router())
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/TEST-CODE")
+	wt.AssertStatus(http.StatusNotFound)
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+	router := app.Router()
+	deck, _ := app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	before := len(deck.Cards)
+
+	login := logIn(t, "tester")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wt := test.NewWebTest(t, *router)
+			wt.Cookies = login
+			wt.SendPost("/newcard", map[string]string{
+				"deck_id":  "TEST-CODE",
+				"question": fmt.Sprintf("Question %d", i),
+				"answer":   "Answer",
+			})
+			wt.AssertRedirectTo("/deck/TEST-CODE")
+			wt = test.NewWebTest(t, *router)
+			wt.SendGet("/deck/TEST-CODE")
+			wt.AssertSuccess()
+		}(i)
+	}
+	wg.Wait()
+
+	deck, _ = app.dataStore.GetDeck(context.Background(), "TEST-CODE")
+	if len(deck.Cards) != before+10 {
+		t.Errorf("Expected %d cards, got %d", before+10, len(deck.Cards))
+	}
+}
+
+// takenCodeStore reports the first few decks it is asked for as existing, as if their codes were taken
+type takenCodeStore struct {
+	platform.DataStore
+	taken int
+}
+
+func (s *takenCodeStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	if s.taken > 0 {
+		s.taken--
+		return cards.Deck{ID: id, Title: "Taken"}, nil
+	}
+	return s.DataStore.GetDeck(ctx, id)
+}
+
+func TestNewDeckCodeCollision(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	login := logIn(t, "tester")
+	store := &takenCodeStore{DataStore: app.dataStore, taken: 2}
+	app.dataStore = store
+
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "testing"})
+
+	wt.AssertRedirectToPrefix("/deck/")
+	deck, err := app.dataStore.GetDeck(context.Background(), strings.TrimPrefix(wt.RedirectTarget(), "/deck/"))
+	if err != nil || deck.Title != "testing" {
+		t.Errorf("New deck not stored after taken codes: %+v, %v", deck, err)
+	}
+
+	store.taken = DECK_CODE_ATTEMPTS
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "testing"})
+	wt.AssertStatus(http.StatusServiceUnavailable)
+}
+
+func TestNewDeckWithSlug(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+	login := logIn(t, "tester")
+
+	wt := test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "Verbs", "slug": " French-Verbs "})
+	wt.AssertRedirectToPrefix("/deck/")
+	deckID := strings.TrimPrefix(wt.RedirectTarget(), "/deck/")
+
+	deck, _ := app.dataStore.GetDeck(ctx, deckID)
+	if deck.Slug != "french-verbs" {
+		t.Errorf("Expected slug french-verbs, got %q", deck.Slug)
+	}
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendGet("/deck/french-verbs?tag=irregular")
+	wt.AssertRedirectTo("/deck/" + deckID + "?tag=irregular")
+
+	wt = test.NewWebTest(t, *router)
+	defer wt.ShowBodyOnFail()
+	wt.SendGet("/deck/" + deckID)
+	wt.AssertBodyContains("#slug", "/deck/french-verbs")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "Other", "slug": "french-verbs"})
+	wt.AssertStatus(http.StatusConflict)
+	wt.AssertBodyContains(".error", "already used by another deck")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "Other", "slug": "ABCD-2345"})
+	wt.AssertStatus(http.StatusBadRequest)
+
+	page, _ := app.dataStore.ListDecks(ctx, platform.DeckQuery{})
+	if len(page.Decks) != 1 {
+		t.Errorf("Refused slugs should not create decks, found %d", len(page.Decks))
+	}
+}
+
+func TestPostEditDeckSlug(t *testing.T) {
+	setupPlatform()
+	test.SetupTestData(context.Background(), app.dataStore, app.logs)
+	router := app.Router()
+	ctx := context.Background()
+
+	for _, slug := range []string{"first-name", "second-name"} {
+		wt := test.NewWebTest(t, *router)
+		wt.Cookies = logIn(t, "tester")
+		wt.SendPost("/editdeck", map[string]string{"deck_id": "TEST-CODE", "title": "Test", "slug": slug})
+		wt.AssertRedirectTo("/deck/TEST-CODE")
+	}
+
+	deck, _ := app.dataStore.GetDeck(ctx, "TEST-CODE")
+	if deck.Slug != "second-name" {
+		t.Errorf("Expected slug second-name, got %q", deck.Slug)
+	}
+	// Links using the earlier slug still work
+	wt := test.NewWebTest(t, *router)
+	wt.SendGet("/deck/first-name")
+	wt.AssertRedirectTo("/deck/TEST-CODE")
+
+	app.dataStore.PutDeck(ctx, "OTHER", cards.Deck{ID: "OTHER", Title: "Other"})
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = logIn(t, "tester")
+	wt.SendPost("/editdeck", map[string]string{"deck_id": "OTHER", "title": "Other", "slug": "first-name"})
+	wt.AssertStatus(http.StatusConflict)
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendGet("/deck/unclaimed-name")
+	wt.AssertStatus(http.StatusNotFound)
+}
+
+// responseCookie is the cookie with the name set by the response, nil if there isn't one
+func responseCookie(wt test.WebTest, name string) *http.Cookie {
+	for _, cookie := range wt.Response.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+
+	wt := test.NewWebTest(t, *router)
+	wt.SendGet("/register")
+	wt.AssertSuccess()
+
+	refused := []struct {
+		fields map[string]string
+		status int
+	}{
+		{map[string]string{"name": "ann", "password": "correct horse", "invite": "wrong"}, http.StatusForbidden},
+		{map[string]string{"name": "ann", "password": "short", "invite": platform.TEST_AUTHOR_KEY}, http.StatusBadRequest},
+		{map[string]string{"name": "a", "password": "correct horse", "invite": platform.TEST_AUTHOR_KEY}, http.StatusBadRequest},
+	}
+	for _, c := range refused {
+		wt = test.NewWebTest(t, *router)
+		wt.SendPost("/register", c.fields)
+		wt.AssertStatus(c.status)
+	}
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendPost("/register", map[string]string{"name": "Ann", "password": "correct horse", "invite": platform.TEST_AUTHOR_KEY})
+	wt.AssertRedirectTo("/")
+	cookie := responseCookie(wt, LOGIN_COOKIE)
+	if cookie == nil || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("Expected a secure login cookie, got %+v", cookie)
+	}
+
+	user, err := app.dataStore.GetUser(ctx, "ann")
+	if err != nil || !user.HasPassword("correct horse") || strings.Contains(user.InvitedBy, platform.TEST_AUTHOR_KEY) {
+		t.Errorf("Unexpected registered user %+v, %v", user, err)
+	}
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = []*http.Cookie{cookie}
+	wt.SendGet("/")
+	wt.AssertBodyContains("#user .name", "ann")
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendPost("/register", map[string]string{"name": "ann", "password": "another one", "invite": platform.TEST_AUTHOR_KEY})
+	wt.AssertStatus(http.StatusConflict)
+	wt.AssertBodyContains(".error", "already taken")
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	setupPlatform()
+	router := app.Router()
+	ctx := context.Background()
+
+	user := cards.User{Name: "ann"}
+	user.SetPassword("correct horse")
+	app.dataStore.PutUser(ctx, user)
+
+	for _, fields := range []map[string]string{
+		{"name": "ann", "password": "wrong horse"},
+		{"name": "bob", "password": "correct horse"},
+		{"name": "ann/../bob", "password": "correct horse"},
+	} {
+		wt := test.NewWebTest(t, *router)
+		wt.SendPost("/login", fields)
+		wt.AssertStatus(http.StatusForbidden)
+		wt.AssertBodyContains(".error", "Wrong user name or password")
+	}
+
+	wt := test.NewWebTest(t, *router)
+	wt.SendPost("/login", map[string]string{"name": " Ann", "password": "correct horse", "next": "//evil.example"})
+	wt.AssertRedirectTo("/")
+
+	wt = test.NewWebTest(t, *router)
+	wt.SendPost("/login", map[string]string{"name": "ann", "password": "correct horse", "next": "/admin/decks"})
+	wt.AssertRedirectTo("/admin/decks")
+	login := []*http.Cookie{responseCookie(wt, LOGIN_COOKIE)}
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "Mine"})
+	wt.AssertRedirectToPrefix("/deck/")
+
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/logout", nil)
+	wt.AssertRedirectTo("/")
+	if cookie := responseCookie(wt, LOGIN_COOKIE); cookie == nil || cookie.MaxAge >= 0 {
+		t.Errorf("Expected the login cookie to be removed, got %+v", cookie)
+	}
+
+	// The login has gone from the store, so the old cookie no longer works
+	wt = test.NewWebTest(t, *router)
+	wt.Cookies = login
+	wt.SendPost("/newdeck", map[string]string{"title": "Mine"})
+	wt.AssertStatus(http.StatusForbidden)
+}
+
+func TestExpiredLogin(t *testing.T) {
+	setupPlatform()
+	ctx := context.Background()
+
+	app.dataStore.PutUser(ctx, cards.User{Name: "ann"})
+	login, token, _ := cards.NewLogin("ann", time.Now().Add(-cards.LOGIN_DURATION-time.Minute))
+	app.dataStore.PutLogin(ctx, login)
+
+	wt := test.NewWebTest(t, *app.Router())
+	wt.Cookies = []*http.Cookie{{Name: LOGIN_COOKIE, Value: token}}
+	wt.SendPost("/newdeck", map[string]string{"title": "Late"})
+	wt.AssertStatus(http.StatusForbidden)
+
+	if _, err := app.dataStore.GetLogin(ctx, login.ID); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected the expired login to be removed, got %v", err)
+	}
+}
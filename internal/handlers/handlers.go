@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/html"
@@ -18,68 +20,128 @@ import (
 )
 
 type pageData struct {
-	Title      string
-	Message    string
-	Error      string
-	Deck       cards.Deck
-	Card       cards.Card
-	Show       string
-	Share      string
-	Question   template.HTML
-	Answer     template.HTML
-	Hint       template.HTML
-	FormAction string
-	History    []string
+	Title        string
+	Message      string
+	Error        string
+	Deck         cards.Deck
+	Card         cards.Card
+	Show         string
+	Share        string
+	Question     template.HTML
+	Answer       template.HTML
+	Hint         template.HTML
+	FormAction   string
+	History      []string
+	Study        studyContext
+	Boxes        []cards.BoxCount
+	StudySession cards.Session
+	Results      []cards.SessionResult
+	Check        *cards.AnswerCheck
+	Prompt       cards.Prompt
+	Prompts      []cards.Prompt
+	Choices      []string
+	Chosen       *choiceResult
+	NextUrl      string
+	Reversible   bool
+	Tags         []cards.TagCount
+	Tree         cards.DeckTree
+	Parent       cards.Deck
+	Trash        []cards.TrashItem
+	ConflictCard *cards.Card // the card as someone else saved it during an edit
+	ConflictDeck *cards.Deck
+	Changes      []cards.Change
+	Query        platform.DeckQuery
+	DeckPage     *platform.DeckPage // nil until the deck listing has been asked for
+	User         *cards.User        // the logged-in user, on pages that show them
+}
+
+type choiceResult struct {
+	Choice  string
+	Correct bool
 }
 
 const HISTORY_COOKIE = "deckHistory"
 
-var logs platform.Logger
-var dataStore platform.DataStore
+// Application serves the flashcards site from one platform's logger and data store. Each Application
+// is independent of any other, so several can run side by side in the same process.
+type Application struct {
+	logs      platform.Logger
+	dataStore platform.DataStore
+}
 
-func ApplicationRouter(platform platform.Platform) *mux.Router {
-	logs = platform.Logger()
-	dataStore = platform.DataStore()
+func NewApplication(platform platform.Platform) *Application {
+	return &Application{
+		logs:      platform.Logger(),
+		dataStore: platform.DataStore(),
+	}
+}
 
+func (app *Application) Router() *mux.Router {
 	r := mux.NewRouter()
 
-	r.HandleFunc("/", homePage)
-	r.HandleFunc("/decks", deckRedirect)
-	r.HandleFunc("/deck/{id}/card/{card}", cardPage)
-	r.HandleFunc("/deck/{id}", deckPage)
-	r.HandleFunc("/random", randomCard)
-	r.HandleFunc("/newcard", addCard)
-	r.HandleFunc("/editcard", editCard)
-	r.HandleFunc("/newdeck", newDeck)
-	r.HandleFunc("/error", errorPage)
-	r.HandleFunc("/qrcode", qrCodeGenerator)
-
-	addStaticAssetRouter(r)
+	r.HandleFunc("/", app.homePage)
+	r.HandleFunc("/decks", app.deckRedirect)
+	r.HandleFunc("/deck/{id}/card/{card}", app.cardPage)
+	r.HandleFunc("/deck/{id}/card/{card}/check", app.checkAnswer)
+	r.HandleFunc("/deck/{id}/card/{card}/choose", app.chooseAnswer).Methods("POST")
+	r.HandleFunc("/deck/{id}/card/{card}/history", app.cardHistory)
+	r.HandleFunc("/deck/{id}/trash", app.trashPage)
+	r.HandleFunc("/deck/{id}/history", app.deckHistory)
+	r.HandleFunc("/deck/{id}", app.deckPage)
+	r.HandleFunc("/random", app.randomCard)
+	r.HandleFunc("/review", app.reviewCard)
+	r.HandleFunc("/leitner", app.leitnerCard)
+	r.HandleFunc("/session", app.newSession)
+	r.HandleFunc("/session/{id}", app.sessionPage)
+	r.HandleFunc("/session/{id}/results", app.sessionResults)
+	r.HandleFunc("/newcard", app.addCard)
+	r.HandleFunc("/editcard", app.editCard)
+	r.HandleFunc("/editdeck", app.editDeck)
+	r.HandleFunc("/newdeck", app.newDeck)
+	r.HandleFunc("/deletecard", app.deleteCard).Methods("POST")
+	r.HandleFunc("/deletedeck", app.deleteDeck).Methods("POST")
+	r.HandleFunc("/admin/decks", app.adminDecks)
+	r.HandleFunc("/register", app.register)
+	r.HandleFunc("/login", app.login)
+	r.HandleFunc("/logout", app.logout).Methods("POST")
+	r.HandleFunc("/error", app.errorPage)
+	r.HandleFunc("/qrcode", app.qrCodeGenerator)
+
+	app.addStaticAssetRouter(r)
+	r.Use(app.loadUser)
 
 	return r
 }
 
-func addStaticAssetRouter(r *mux.Router) {
-	staticDir := http.Dir(platform.TemplateDir(logs) + "/static")
-	logs.Debug(context.Background(), "Static files in %v", staticDir)
+func (app *Application) addStaticAssetRouter(r *mux.Router) {
+	staticDir := http.Dir(platform.TemplateDir(app.logs) + "/static")
+	app.logs.Debug(context.Background(), "Static files in %v", staticDir)
 	fs := http.FileServer(staticDir)
 
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
 }
 
-func showTemplatePage(templateName string, data any, w http.ResponseWriter) {
-	dir := platform.TemplateDir(logs)
+func (app *Application) showTemplatePage(templateName string, data any, w http.ResponseWriter) {
+	app.showTemplatePageWithStatus(templateName, data, http.StatusOK, w)
+}
+
+func (app *Application) showTemplatePageWithStatus(templateName string, data any, status int, w http.ResponseWriter) {
+	dir := platform.TemplateDir(app.logs)
 	t, err := template.ParseFiles(dir+"/base.html", dir+"/"+templateName+".html")
 	if err != nil {
 		msg := http.StatusText(http.StatusInternalServerError)
-		logs.Error(context.Background(), "Error parsing template: %+v", err)
+		app.logs.Error(context.Background(), "Error parsing template: %+v", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 		return
 	}
 
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+
 	if err := t.ExecuteTemplate(w, "base", data); err != nil {
 		msg := http.StatusText(http.StatusInternalServerError)
-		logs.Error(context.Background(), "template.Execute: %v", err)
+		app.logs.Error(context.Background(), "template.Execute: %v", err)
 		http.Error(w, msg, http.StatusInternalServerError)
 	}
 }
@@ -89,55 +151,94 @@ func requestContext(r *http.Request) context.Context {
 }
 
 // show home/index page
-func homePage(w http.ResponseWriter, r *http.Request) {
+func (app *Application) homePage(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 
-	logs.Debug(ctx, "Received request: %s %s", r.Method, r.URL.Path)
+	app.logs.Debug(ctx, "Received request: %s %s", r.Method, r.URL.Path)
 
 	data := pageData{
 		Message: "Fashcards",
-		History: getHistory(HISTORY_COOKIE, r).entries,
+		History: app.getHistory(HISTORY_COOKIE, r).entries,
+		User:    pageUser(r),
 	}
 
-	showTemplatePage("index", data, w)
+	app.showTemplatePage("index", data, w)
 }
 
-func deckRedirect(w http.ResponseWriter, r *http.Request) {
+func (app *Application) deckRedirect(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 	deckId := r.FormValue("deck")
 	deckUrl := fmt.Sprintf("/deck/%s", strings.ToUpper(deckId))
-	logs.Debug(ctx, "Redirecting to %s", deckUrl)
+	app.logs.Debug(ctx, "Redirecting to %s", deckUrl)
 	http.Redirect(w, r, deckUrl, http.StatusSeeOther)
 }
 
-func deckPage(w http.ResponseWriter, r *http.Request) {
+func (app *Application) deckPage(w http.ResponseWriter, r *http.Request) {
 	ctx := context.WithValue(r.Context(), platform.HttpRequestKey, r)
 
-	logs.Debug(ctx, "Deck page %s", r.RequestURI)
+	app.logs.Debug(ctx, "Deck page %s", r.RequestURI)
 	deckID := mux.Vars(r)["id"]
 
-	logs.Debug(ctx, "Showing deck %s", deckID)
+	app.logs.Debug(ctx, "Showing deck %s", deckID)
 
 	var shareUrl string
 	if r.FormValue("share") == "true" {
 		shareUrl = deckUrl(r, deckID)
 	}
 
+	deck, err := app.dataStore.GetDeck(ctx, deckID)
+	if errors.Is(err, platform.ErrNotFound) {
+		if target, ok := app.slugTarget(ctx, deckID); ok {
+			app.logs.Debug(ctx, "Redirecting slug %s to deck %s", deckID, target)
+			target = "/deck/" + target
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusSeeOther)
+			return
+		}
+	}
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
 	data := pageData{
-		Deck:  dataStore.GetDeck(ctx, deckID),
+		Deck:  deck,
 		Share: shareUrl,
+		User:  pageUser(r),
 	}
+	data.Title = data.Deck.Title
+	data.Study = getStudyContext(r)
 
-	if data.Deck.ID != deckID {
-		http.Redirect(w, r, "/error?code=2001", http.StatusSeeOther)
+	data.Tree, err = app.deckTree(ctx, data.Deck)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	if data.Deck.ParentID != "" {
+		// A missing parent just means no link back up the tree
+		data.Parent, _ = app.dataStore.GetDeck(ctx, data.Deck.ParentID)
+	}
+
+	// The deck's own cards are listed, but studying covers the sub-decks too
+	studyDeck := data.Tree.StudyDeck()
+	studying := studyPrompts(studyDeck, data.Study)
+	data.Prompts = studyPrompts(data.Deck, data.Study)
+	data.Reversible = len(cards.InDirection(studying, cards.REVERSE)) > 0
+	data.Tags = studyDeck.TagCounts()
+	progress, err := app.dataStore.GetProgress(ctx, learnerID(w, r), deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
 		return
 	}
+	data.Boxes = progress.BoxCounts(studying)
 
-	history := getHistory(HISTORY_COOKIE, r)
+	history := app.getHistory(HISTORY_COOKIE, r)
 	history.push(deckID)
 	history.setCookie(w)
 
-	showTemplatePage("deck", data, w)
+	app.showTemplatePage("deck", data, w)
 }
 
 func deckUrl(r *http.Request, deckID string) string {
@@ -148,25 +249,24 @@ func deckUrl(r *http.Request, deckID string) string {
 	}
 }
 
-func cardPage(w http.ResponseWriter, r *http.Request) {
+func (app *Application) cardPage(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 
 	deckID := mux.Vars(r)["id"]
 	cardID := mux.Vars(r)["card"]
 
-	logs.Debug(ctx, "Showing card %s from deck %s", cardID, deckID)
+	app.logs.Debug(ctx, "Showing card %s from deck %s", cardID, deckID)
 
-	deck := dataStore.GetDeck(context.Background(), deckID)
-
-	if deck.ID != deckID {
-		http.Redirect(w, r, "/error?code=2001", http.StatusSeeOther)
+	deck, err := app.getStudyDeck(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
 		return
 	}
 
 	card := deck.GetCard(cardID)
 
 	if card.ID != cardID {
-		http.Redirect(w, r, "/error?code=2002", http.StatusSeeOther)
+		app.showError(w, r, "2002")
 		return
 	}
 
@@ -175,15 +275,106 @@ func cardPage(w http.ResponseWriter, r *http.Request) {
 		show = "none"
 	}
 
+	study := getStudyContext(r)
+	prompt := cards.NewPrompt(card, study.Cloze, study.Reversed)
+
+	app.showCard(w, r, deck, prompt, show, pageData{})
+}
+
+// checkAnswer compares the learner's typed answer with the card, then shows the answer along with the comparison
+func (app *Application) checkAnswer(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	deckID := mux.Vars(r)["id"]
+	cardID := mux.Vars(r)["card"]
+
+	deck, err := app.getStudyDeck(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	card := deck.GetCard(cardID)
+	if card.ID != cardID {
+		app.showError(w, r, "2002")
+		return
+	}
+
+	study := getStudyContext(r)
+	prompt := cards.NewPrompt(card, study.Cloze, study.Reversed)
+
+	check := cards.CheckAnswer(prompt.AcceptableAnswers(), r.FormValue("typed"))
+	app.logs.Debug(ctx, "Checked typed answer for prompt %s, distance %d, correct=%v", prompt.Key(), check.Distance, check.Correct)
+
+	app.showCard(w, r, deck, prompt, "show", pageData{Check: &check})
+}
+
+// chooseAnswer marks the learner's pick for a multiple-choice card and records the result against their progress
+func (app *Application) chooseAnswer(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	deckID := mux.Vars(r)["id"]
+	cardID := mux.Vars(r)["card"]
+
+	deck, err := app.getStudyDeck(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	card := deck.GetCard(cardID)
+	if card.ID != cardID {
+		app.showError(w, r, "2002")
+		return
+	}
+
+	study := getStudyContext(r)
+	prompt := cards.NewPrompt(card, study.Cloze, study.Reversed)
+	chosen := choiceResult{
+		Choice:  r.FormValue("choice"),
+		Correct: card.IsCorrectChoice(r.FormValue("choice")),
+	}
+
+	app.logs.Debug(ctx, "Multiple choice answer for prompt %s, correct=%v", prompt.Key(), chosen.Correct)
+
+	if err := app.recordResult(ctx, learnerID(w, r), deckID, prompt.Key(), study, chosen.Correct); err != nil {
+		app.showStoreError(w, r, err, "2003")
+		return
+	}
+
+	app.showCard(w, r, deck, prompt, "show", pageData{Chosen: &chosen, NextUrl: nextUrl(deckID, study)})
+}
+
+// showCard shows the card page for a prompt, along with any results of answer checking in the extra page data
+func (app *Application) showCard(w http.ResponseWriter, r *http.Request, deck cards.Deck, prompt cards.Prompt, show string, extra pageData) {
+	ctx := requestContext(r)
+
+	study := getStudyContext(r)
+	study.Cloze = prompt.Cloze
+	study.Reversed = prompt.Reversed
+
 	data := pageData{
+		Title:    deck.Title + " - Card",
 		Deck:     deck,
-		Card:     card,
+		Card:     prompt.Card,
+		Prompt:   prompt,
 		Show:     show,
-		Question: renderMarkdown(card.Question),
-		Answer:   renderMarkdown(card.Answer),
-		Hint:     renderMarkdown(card.Hint),
+		Study:    study,
+		Question: renderMarkdown(prompt.Question()),
+		Answer:   renderMarkdown(prompt.Answer()),
+		Hint:     renderMarkdown(prompt.Card.Hint),
+		Check:    extra.Check,
+		Chosen:   extra.Chosen,
+		NextUrl:  extra.NextUrl,
 	}
-	showTemplatePage("card", data, w)
+	if prompt.Card.IsChoice() && show != "show" {
+		data.Choices = deck.Choices(prompt.Card)
+	}
+	if data.Study.Session != "" {
+		// Without the session the card is still shown, just not the session progress
+		data.StudySession, _ = app.dataStore.GetSession(ctx, data.Study.Session)
+	}
+	app.showTemplatePage("card", data, w)
 }
 
 func renderMarkdown(source string) template.HTML {
@@ -204,34 +395,131 @@ func renderMarkdown(source string) template.HTML {
 	return htmlFragment
 }
 
-func randomCard(w http.ResponseWriter, r *http.Request) {
+func (app *Application) randomCard(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 
 	deckId := strings.ToUpper(r.FormValue("deck"))
 
-	deck := dataStore.GetDeck(context.Background(), deckId)
-
-	if deck.ID == "" {
-		logs.Error(ctx, "Could not fetch deck %s", deckId)
+	deck, err := app.getStudyDeck(ctx, deckId)
+	if errors.Is(err, platform.ErrNotFound) {
+		app.logs.Error(ctx, "Could not fetch deck %s", deckId)
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
+	} else if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
 	}
 
-	logs.Info(ctx, "Showing random card for %s", deck.Title)
+	app.logs.Info(ctx, "Showing next due card for %s", deck.Title)
 
-	card := deck.RandomCard()
+	filters := getStudyContext(r)
+	study := studyContext{Direction: filters.Direction, Tags: filters.Tags}
+	progress, err := app.dataStore.GetProgress(ctx, learnerID(w, r), deckId)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	prompt := progress.NextDue(studyPrompts(deck, study), time.Now())
 
-	http.Redirect(w, r, "/deck/"+deckId+"/card/"+card.ID+"?answer=hide", http.StatusSeeOther)
+	http.Redirect(w, r, promptUrl(deckId, prompt.Key(), study), http.StatusSeeOther)
 }
 
-func addCard(w http.ResponseWriter, r *http.Request) {
+func (app *Application) reviewCard(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 
+	r.ParseForm()
+	deckID := r.Form.Get("deck_id")
+	key := r.Form.Get("card_id")
+
+	grade, ok := cards.ParseGrade(r.Form.Get("grade"))
+	if !ok {
+		app.showError(w, r, "1001")
+		return
+	}
+	if !app.checkStudyCard(w, r, deckID, key) {
+		return
+	}
+
+	learner := learnerID(w, r)
+	app.logs.Debug(ctx, "Learner %s graded prompt %s in deck %s as %d", learner, key, deckID, grade)
+
+	progress, err := app.dataStore.GetProgress(ctx, learner, deckID)
+	if err == nil {
+		progress.Grade(key, grade, time.Now())
+		err = app.dataStore.PutProgress(ctx, progress)
+	}
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	http.Redirect(w, r, nextUrl(deckID, getStudyContext(r)), http.StatusSeeOther)
+}
+
+// leitnerCard shows a card chosen by Leitner box, and records right/wrong answers posted back from the card page
+func (app *Application) leitnerCard(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	learner := learnerID(w, r)
+
 	if r.Method == "POST" {
 		r.ParseForm()
 		deckID := r.Form.Get("deck_id")
+		key := r.Form.Get("card_id")
+		correct := r.Form.Get("result") == "right"
+
+		app.logs.Debug(ctx, "Learner %s answered prompt %s in deck %s, correct=%v", learner, key, deckID, correct)
+		if !app.checkStudyCard(w, r, deckID, key) {
+			return
+		}
+
+		progress, err := app.dataStore.GetProgress(ctx, learner, deckID)
+		if err == nil {
+			progress.Leitner(key, correct, time.Now())
+			err = app.dataStore.PutProgress(ctx, progress)
+		}
+		if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+
+		filters := getStudyContext(r)
+		study := studyContext{Mode: "leitner", Direction: filters.Direction, Tags: filters.Tags}
+		http.Redirect(w, r, nextUrl(deckID, study), http.StatusSeeOther)
+	} else {
+		deckID := strings.ToUpper(r.FormValue("deck"))
+
+		deck, err := app.getStudyDeck(ctx, deckID)
+		if errors.Is(err, platform.ErrNotFound) {
+			app.logs.Error(ctx, "Could not fetch deck %s", deckID)
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		} else if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+
+		filters := getStudyContext(r)
+		study := studyContext{Mode: "leitner", Direction: filters.Direction, Tags: filters.Tags}
+		progress, err := app.dataStore.GetProgress(ctx, learner, deckID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+		prompt := progress.LeitnerPrompt(studyPrompts(deck, study))
 
-		deck := dataStore.GetDeck(context.Background(), deckID)
+		http.Redirect(w, r, promptUrl(deckID, prompt.Key(), study), http.StatusSeeOther)
+	}
+}
+
+func (app *Application) addCard(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	if r.Method == "POST" {
+		if !app.requireUser(w, r) {
+			return
+		}
+		r.ParseForm()
+		deckID := r.Form.Get("deck_id")
 
 		card := cards.Card{
 			ID:     cards.RandomCardId(),
@@ -239,97 +527,247 @@ func addCard(w http.ResponseWriter, r *http.Request) {
 		}
 		updateCardFromForm(&card, r)
 
-		deck.AddCard(card)
-
-		dataStore.PutDeck(context.Background(), deck.ID, deck)
+		if err := app.dataStore.PutCard(ctx, deckID, card); err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+		app.recordChange(r, cards.CardChange(cards.CHANGE_CREATED, nil, written(card, deckID), actorID(r), time.Now()))
 
 		http.Redirect(w, r, "/deck/"+deckID, http.StatusSeeOther)
 	} else {
 		deckID := strings.ToUpper(r.FormValue("deck"))
-		logs.Debug(ctx, "Showing new card page for %s", deckID)
-		deck := dataStore.GetDeck(context.Background(), deckID)
+		app.logs.Debug(ctx, "Showing new card page for %s", deckID)
+		deck, err := app.dataStore.GetDeck(ctx, deckID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
 		data := pageData{
 			Deck:       deck,
 			Card:       *new(cards.Card),
 			FormAction: "/newcard",
 		}
-		showTemplatePage("editcard", data, w)
+		app.showTemplatePage("editcard", data, w)
 	}
 }
 
-func editCard(w http.ResponseWriter, r *http.Request) {
+func (app *Application) editCard(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 
 	if r.Method == "POST" {
+		if !app.requireUser(w, r) {
+			return
+		}
 		r.ParseForm()
 		deckID := r.Form.Get("deck_id")
 		cardID := r.Form.Get("card_id")
 
-		logs.Info(ctx, "Received edit for card %s in deck %s", cardID, deckID)
-
-		deck := dataStore.GetDeck(context.Background(), deckID)
+		app.logs.Info(ctx, "Received edit for card %s in deck %s", cardID, deckID)
 
-		card := deck.GetCard(cardID)
+		card, err := app.dataStore.GetCard(ctx, deckID, cardID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2002")
+			return
+		}
 
+		before := card
 		updateCardFromForm(&card, r)
 
-		deck.PutCard(card.ID, card)
+		if formVersion(r, card.Version) != card.Version {
+			app.showCardConflict(w, r, card)
+			return
+		}
 
-		dataStore.PutDeck(context.Background(), deck.ID, deck)
+		err = app.dataStore.PutCard(ctx, deckID, card)
+		if errors.Is(err, platform.ErrConflict) {
+			app.showCardConflict(w, r, card)
+			return
+		} else if err != nil {
+			app.showStoreError(w, r, err, "2002")
+			return
+		}
+		app.recordChange(r, cards.CardChange(cards.CHANGE_EDITED, &before, written(card, deckID), actorID(r), time.Now()))
 
 		http.Redirect(w, r, "/deck/"+deckID+"/card/"+cardID+"?answer=show", http.StatusSeeOther)
 	} else {
 		deckID := strings.ToUpper(r.FormValue("deck"))
 		cardID := strings.ToUpper(r.FormValue("card"))
-		logs.Debug(ctx, "Showing edit card page for %s / %s", deckID, cardID)
-		deck := dataStore.GetDeck(context.Background(), deckID)
+		app.logs.Debug(ctx, "Showing edit card page for %s / %s", deckID, cardID)
+		deck, err := app.dataStore.GetDeck(ctx, deckID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
 		data := pageData{
 			Deck:       deck,
 			Card:       deck.GetCard(cardID),
 			FormAction: "/editcard",
 		}
-		showTemplatePage("editcard", data, w)
+		if data.Card.ID != cardID {
+			app.showError(w, r, "2002")
+			return
+		}
+		app.showTemplatePage("editcard", data, w)
 	}
 }
 
 func updateCardFromForm(card *cards.Card, r *http.Request) {
+	card.Type = r.Form.Get("type")
 	card.Question = r.Form.Get("question")
 	card.Answer = r.Form.Get("answer")
 	card.Hint = r.Form.Get("hint")
+	card.Alternatives = formLines(r.Form.Get("alternatives"))
+	card.Choices = formLines(r.Form.Get("choices"))
+	card.Tags = cards.ParseTags(r.Form.Get("tags"))
+	switch r.Form.Get("reversible") {
+	case "yes":
+		reversible := true
+		card.Reversible = &reversible
+	case "no":
+		reversible := false
+		card.Reversible = &reversible
+	default:
+		card.Reversible = nil
+	}
+}
+
+// formLines splits a textarea value into its non-blank lines
+func formLines(text string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (app *Application) editDeck(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	if r.Method == "POST" {
+		if !app.requireUser(w, r) {
+			return
+		}
+		r.ParseForm()
+		deckID := r.Form.Get("deck_id")
+
+		app.logs.Info(ctx, "Received edit for deck %s", deckID)
+
+		deck, err := app.dataStore.GetDeck(ctx, deckID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+
+		before := deck
+		deck.Title = r.Form.Get("title")
+		deck.Reversible = r.Form.Get("reversible") == "true"
+		deck.Slug = cards.NormaliseSlug(r.Form.Get("slug"))
+		// Only the deck's own settings have changed, the cards are left as they are
+		deck.Cards = nil
+
+		if formVersion(r, deck.Version) != deck.Version {
+			app.showDeckConflict(w, r, deck)
+			return
+		}
+		// The claim is kept even if saving the deck fails, but it only ever leads to this deck
+		if deck.Slug != "" && deck.Slug != before.Slug && !app.claimSlug(w, r, deck.Slug, deck.ID) {
+			return
+		}
+
+		err = app.dataStore.PutDeck(ctx, deck.ID, deck)
+		if errors.Is(err, platform.ErrConflict) {
+			app.showDeckConflict(w, r, deck)
+			return
+		} else if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+		after := deck
+		after.Version++
+		app.recordChange(r, cards.DeckChange(cards.CHANGE_EDITED, &before, &after, actorID(r), time.Now()))
+
+		http.Redirect(w, r, "/deck/"+deckID, http.StatusSeeOther)
+	} else {
+		deckID := strings.ToUpper(r.FormValue("deck"))
+		app.logs.Debug(ctx, "Showing edit deck page for %s", deckID)
+		deck, err := app.dataStore.GetDeck(ctx, deckID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+		data := pageData{
+			Title: deck.Title,
+			Deck:  deck,
+		}
+		app.showTemplatePage("editdeck", data, w)
+	}
 }
 
-func newDeck(w http.ResponseWriter, r *http.Request) {
+func (app *Application) newDeck(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 	r.ParseForm()
 
 	deck := cards.Deck{
-		ID:    cards.RandomDeckId(),
-		Title: r.Form.Get("title"),
+		Title:      r.Form.Get("title"),
+		Reversible: r.Form.Get("reversible") == "true",
+		ParentID:   strings.ToUpper(r.Form.Get("parent")),
 	}
+	slug := cards.NormaliseSlug(r.Form.Get("slug"))
 
-	if !dataStore.IsValidAuthor(r.Form.Get("author")) {
-		http.Redirect(w, r, "/error?code=3001", http.StatusSeeOther)
+	user, ok := currentUser(r)
+	if !ok {
+		app.showError(w, r, "3001")
 		return
 	}
+	deck.CreatedBy = user.Name
 
-	logs.Info(ctx, "Creating deck %s with title %s", deck.ID, deck.Title)
+	if deck.ParentID != "" {
+		if _, err := app.dataStore.GetDeck(ctx, deck.ParentID); err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+	}
 
-	dataStore.PutDeck(context.Background(), deck.ID, deck)
+	// Checking first means a slug that is taken is refused before the deck is created
+	if slug != "" && !app.checkSlug(w, r, slug, "") {
+		return
+	}
+
+	deck, err := app.createDeck(ctx, deck)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	app.logs.Info(ctx, "Created deck %s with title %s", deck.ID, deck.Title)
+	deck.Version++
+
+	if slug != "" {
+		if !app.claimSlug(w, r, slug, deck.ID) {
+			return
+		}
+		deck.Slug = slug
+		if err := app.dataStore.PutDeck(ctx, deck.ID, deck); err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+		deck.Version++
+	}
+	app.recordChange(r, cards.DeckChange(cards.CHANGE_CREATED, nil, &deck, actorID(r), time.Now()))
 
 	http.Redirect(w, r, "/deck/"+deck.ID, http.StatusSeeOther)
 }
 
-func errorPage(w http.ResponseWriter, r *http.Request) {
+func (app *Application) errorPage(w http.ResponseWriter, r *http.Request) {
 	ctx := requestContext(r)
 	errorCode := r.FormValue("code")
-	data := pageData{
-		Error: errorText(errorCode),
-	}
-	logs.Info(ctx, "Showing error page %s %s", errorCode, data.Error)
-	showTemplatePage("error", data, w)
+	app.logs.Debug(ctx, "Error page requested for %s", errorCode)
+	app.showError(w, r, errorCode)
 }
 
-func qrCodeGenerator(w http.ResponseWriter, r *http.Request) {
+func (app *Application) qrCodeGenerator(w http.ResponseWriter, r *http.Request) {
 	deckID := strings.ToUpper(r.FormValue("deck"))
 
 	gameUrl := deckUrl(r, deckID)
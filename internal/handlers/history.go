@@ -10,14 +10,14 @@ type History struct {
 	entries    []string
 }
 
-func getHistory(cookieName string, r *http.Request) History {
+func (app *Application) getHistory(cookieName string, r *http.Request) History {
 	ctx := requestContext(r)
 	var history History
 	history.cookieName = cookieName
 	current, err := r.Cookie(cookieName)
 	if err != http.ErrNoCookie {
 		history.entries = strings.Split(current.Value, "|")
-		logs.Debug(ctx, "Loaded  history: %v", history.entries)
+		app.logs.Debug(ctx, "Loaded  history: %v", history.entries)
 	}
 	return history
 }
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+)
+
+const LEARNER_COOKIE = "learnerId"
+
+// learnerIDPattern matches the IDs that learnerID hands out, as the ID is used as a data store key
+var learnerIDPattern = regexp.MustCompile(`^[0-9A-F]{16}$`)
+
+// learnerID identifies the current learner by a long-lived cookie, assigning a new ID if there isn't
+// one yet or the cookie holds something else.
+func learnerID(w http.ResponseWriter, r *http.Request) string {
+	current, err := r.Cookie(LEARNER_COOKIE)
+	if err == nil && learnerIDPattern.MatchString(current.Value) {
+		return current.Value
+	}
+
+	id := fmt.Sprintf("%08X%08X", rand.Intn(0xFFFFFFFF), rand.Intn(0xFFFFFFFF))
+	cookie := http.Cookie{
+		Name:     LEARNER_COOKIE,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	http.SetCookie(w, &cookie)
+
+	return id
+}
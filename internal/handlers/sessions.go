@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"flashcards/internal/cards"
+)
+
+// newSession starts a study session covering the whole deck, or just the missed cards of an earlier session
+func (app *Application) newSession(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	deckID := strings.ToUpper(r.FormValue("deck"))
+	deck, err := app.getStudyDeck(ctx, deckID)
+	if err != nil {
+		app.logs.Error(ctx, "Could not fetch deck %s", deckID)
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	var session cards.Session
+	retryID := r.FormValue("retry")
+	if retryID != "" {
+		previous, err := app.dataStore.GetSession(ctx, retryID)
+		if err != nil {
+			app.showStoreError(w, r, err, "2003")
+			return
+		}
+		session = cards.NewSessionForKeys(deck.ID, previous.Missed())
+	} else {
+		session = cards.NewSession(deck.ID, studyPrompts(deck, getStudyContext(r)))
+	}
+
+	app.logs.Info(ctx, "Starting session %s with %d cards from deck %s", session.ID, len(session.Order), deck.ID)
+
+	if err := app.dataStore.PutSession(ctx, session); err != nil {
+		app.showStoreError(w, r, err, "2003")
+		return
+	}
+
+	http.Redirect(w, r, "/session/"+session.ID, http.StatusSeeOther)
+}
+
+// sessionPage moves the learner on to the next card in the session, first recording the outcome for the current card if one was posted
+func (app *Application) sessionPage(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	sessionID := mux.Vars(r)["id"]
+	session, err := app.dataStore.GetSession(ctx, sessionID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2003")
+		return
+	}
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		key := r.Form.Get("card_id")
+		outcome := cards.Wrong
+		if r.Form.Get("result") == "right" {
+			if r.Form.Get("hinted") == "true" {
+				outcome = cards.Hinted
+			} else {
+				outcome = cards.Right
+			}
+		}
+
+		if session.Record(key, outcome) {
+			app.logs.Debug(ctx, "Session %s recorded %s for prompt %s", session.ID, outcome, key)
+			if err := app.dataStore.PutSession(ctx, session); err != nil {
+				app.showStoreError(w, r, err, "2003")
+				return
+			}
+		}
+	}
+
+	if session.Finished() {
+		http.Redirect(w, r, "/session/"+session.ID+"/results", http.StatusSeeOther)
+		return
+	}
+
+	study := studyContext{Mode: "session", Session: session.ID}
+	http.Redirect(w, r, promptUrl(session.DeckID, session.Current(), study), http.StatusSeeOther)
+}
+
+func (app *Application) sessionResults(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+
+	sessionID := mux.Vars(r)["id"]
+	session, err := app.dataStore.GetSession(ctx, sessionID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2003")
+		return
+	}
+
+	deck, err := app.getStudyDeck(ctx, session.DeckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	data := pageData{
+		Title:        deck.Title + " - Results",
+		Deck:         deck,
+		StudySession: session,
+		Results:      session.Summary(deck),
+	}
+	app.showTemplatePage("results", data, w)
+}
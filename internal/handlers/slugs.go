@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// How many random codes are tried for a new deck before giving up, each one having already been
+// taken being very unlikely
+const DECK_CODE_ATTEMPTS = 10
+
+// createDeck stores a new deck under a random code that no other deck has, trying another code if
+// one is taken, including by a deck created at the same moment.
+func (app *Application) createDeck(ctx context.Context, deck cards.Deck) (cards.Deck, error) {
+	for attempt := 0; attempt < DECK_CODE_ATTEMPTS; attempt++ {
+		deck.ID = cards.RandomDeckId()
+		_, err := app.dataStore.GetDeck(ctx, deck.ID)
+		if err == nil {
+			app.logs.Info(ctx, "Deck code %s is taken, trying another", deck.ID)
+			continue
+		} else if !errors.Is(err, platform.ErrNotFound) {
+			return deck, err
+		}
+
+		// A new deck is written at version 0, which conflicts with any deck stored since the check
+		err = app.dataStore.PutDeck(ctx, deck.ID, deck)
+		if errors.Is(err, platform.ErrConflict) {
+			app.logs.Info(ctx, "Deck code %s was taken at the same time, trying another", deck.ID)
+			continue
+		}
+		return deck, err
+	}
+	return deck, fmt.Errorf("no unused deck code after %d attempts, %w", DECK_CODE_ATTEMPTS, platform.ErrUnavailable)
+}
+
+// checkSlug makes sure a normalised slug can be claimed, showing the error page and returning false
+// if it is not allowed or another deck already has it
+func (app *Application) checkSlug(w http.ResponseWriter, r *http.Request, slug string, deckID string) bool {
+	ctx := requestContext(r)
+	if err := cards.CheckSlug(slug); err != nil {
+		app.logs.Info(ctx, "Refusing slug %q, %v", slug, err)
+		app.showError(w, r, "1002")
+		return false
+	}
+	current, err := app.dataStore.GetSlug(ctx, slug)
+	if err == nil && current != deckID {
+		app.showError(w, r, "4002")
+		return false
+	} else if err != nil && !errors.Is(err, platform.ErrNotFound) {
+		app.showStoreError(w, r, err, "2001")
+		return false
+	}
+	return true
+}
+
+// claimSlug claims a slug for the deck, showing the error page and returning false if it cannot
+func (app *Application) claimSlug(w http.ResponseWriter, r *http.Request, slug string, deckID string) bool {
+	if !app.checkSlug(w, r, slug, deckID) {
+		return false
+	}
+	err := app.dataStore.PutSlug(requestContext(r), slug, deckID)
+	if errors.Is(err, platform.ErrConflict) {
+		app.showError(w, r, "4002")
+		return false
+	} else if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return false
+	}
+	return true
+}
+
+// slugTarget finds the deck that claimed a slug, as long as the text could be one
+func (app *Application) slugTarget(ctx context.Context, text string) (string, bool) {
+	slug := cards.NormaliseSlug(text)
+	if cards.CheckSlug(slug) != nil {
+		return "", false
+	}
+	deckID, err := app.dataStore.GetSlug(ctx, slug)
+	if err != nil {
+		return "", false
+	}
+	return deckID, true
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashcards/internal/cards"
+)
+
+// studyContext carries how a card is being studied from one card page request to the next.
+type studyContext struct {
+	Mode      string
+	Session   string
+	Hinted    bool
+	Cloze     int
+	Reversed  bool
+	Direction string
+	Tags      []string
+}
+
+func getStudyContext(r *http.Request) studyContext {
+	r.ParseForm()
+	cloze, _ := strconv.Atoi(r.FormValue("cloze"))
+	direction := r.FormValue("direction")
+	return studyContext{
+		Mode:      r.FormValue("mode"),
+		Session:   r.FormValue("session"),
+		Hinted:    r.FormValue("hinted") == "true",
+		Cloze:     cloze,
+		Reversed:  r.FormValue("reversed") == "true" || direction == cards.REVERSE,
+		Direction: direction,
+		Tags:      cards.ParseTags(strings.Join(r.Form["tag"], ",")),
+	}
+}
+
+// Query encodes the study context for use in card page links.
+func (study studyContext) Query() template.URL {
+	values := study.filters()
+	if study.Cloze > 0 {
+		values.Set("cloze", strconv.Itoa(study.Cloze))
+	}
+	if study.Mode != "" {
+		values.Set("mode", study.Mode)
+	}
+	if study.Session != "" {
+		values.Set("session", study.Session)
+	}
+	if study.Reversed {
+		values.Set("reversed", "true")
+	}
+	return template.URL(values.Encode())
+}
+
+// filters encodes just the choices that restrict which prompts are studied.
+func (study studyContext) filters() url.Values {
+	values := url.Values{}
+	if study.Direction != "" {
+		values.Set("direction", study.Direction)
+	}
+	if len(study.Tags) > 0 {
+		values["tag"] = study.Tags
+	}
+	return values
+}
+
+// FilterQuery encodes the prompt filters for use in study links.
+func (study studyContext) FilterQuery() template.URL {
+	return template.URL(study.filters().Encode())
+}
+
+// promptUrl links to the card page for the prompt with the given key, with the answer hidden
+func promptUrl(deckID string, key string, study studyContext) string {
+	cardID, cloze, reversed := cards.ParsePromptKey(key)
+	study.Cloze = cloze
+	study.Reversed = reversed
+	link := "/deck/" + deckID + "/card/" + cardID + "?answer=hide"
+	if query := study.Query(); query != "" {
+		link = link + "&" + string(query)
+	}
+	return link
+}
+
+// deckTree fetches all of the sub-decks below the deck, returning the first error from fetching them
+func (app *Application) deckTree(ctx context.Context, deck cards.Deck) (cards.DeckTree, error) {
+	var err error
+	tree := cards.BuildTree(deck, func(parentID string) []cards.Deck {
+		children, childErr := app.dataStore.GetChildDecks(ctx, parentID)
+		if err == nil {
+			err = childErr
+		}
+		return children
+	})
+	return tree, err
+}
+
+// getStudyDeck fetches the deck with the cards of all its sub-decks merged in, so that studying a deck covers its sub-decks too
+func (app *Application) getStudyDeck(ctx context.Context, deckID string) (cards.Deck, error) {
+	deck, err := app.dataStore.GetDeck(ctx, deckID)
+	if err != nil {
+		return deck, err
+	}
+	tree, err := app.deckTree(ctx, deck)
+	return tree.StudyDeck(), err
+}
+
+// checkStudyCard makes sure the card a posted prompt key belongs to is in the deck being studied or
+// one of its sub-decks, showing the not found page and returning false if it is not
+func (app *Application) checkStudyCard(w http.ResponseWriter, r *http.Request, deckID string, key string) bool {
+	deck, err := app.getStudyDeck(requestContext(r), deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return false
+	}
+	cardID, _, _ := cards.ParsePromptKey(key)
+	if _, ok := deck.Cards[cardID]; !ok {
+		app.showError(w, r, "2002")
+		return false
+	}
+	return true
+}
+
+// studyPrompts returns the prompts from the deck that the learner has chosen to study
+func studyPrompts(deck cards.Deck, study studyContext) []cards.Prompt {
+	return cards.WithTags(cards.InDirection(deck.Prompts(), study.Direction), study.Tags)
+}
+
+// nextUrl is where the learner goes after finishing with a card, depending on how they are studying
+func nextUrl(deckID string, study studyContext) string {
+	var link string
+	switch study.Mode {
+	case "session":
+		return "/session/" + study.Session
+	case "leitner":
+		link = "/leitner?deck=" + deckID
+	default:
+		link = "/random?deck=" + deckID
+	}
+	if query := study.filters().Encode(); query != "" {
+		link = link + "&" + query
+	}
+	return link
+}
+
+// recordResult feeds an automatically checked answer into the progress tracking for the current study mode
+func (app *Application) recordResult(ctx context.Context, learner string, deckID string, key string, study studyContext, correct bool) error {
+	if study.Mode == "session" {
+		session, err := app.dataStore.GetSession(ctx, study.Session)
+		if err != nil {
+			return err
+		}
+		outcome := cards.Wrong
+		if correct {
+			outcome = cards.Right
+		}
+		if session.Record(key, outcome) {
+			return app.dataStore.PutSession(ctx, session)
+		}
+		return nil
+	}
+
+	progress, err := app.dataStore.GetProgress(ctx, learner, deckID)
+	if err != nil {
+		return err
+	}
+	if study.Mode == "leitner" {
+		progress.Leitner(key, correct, time.Now())
+	} else {
+		grade := cards.Again
+		if correct {
+			grade = cards.Good
+		}
+		progress.Grade(key, grade, time.Now())
+	}
+	return app.dataStore.PutProgress(ctx, progress)
+}
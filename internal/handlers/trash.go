@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// deleteCard moves a card from its deck into the deck's trash
+func (app *Application) deleteCard(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	if !app.requireUser(w, r) {
+		return
+	}
+
+	r.ParseForm()
+	deckID := r.Form.Get("deck_id")
+	cardID := r.Form.Get("card_id")
+
+	card, err := app.dataStore.GetCard(ctx, deckID, cardID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2002")
+		return
+	}
+
+	app.logs.Info(ctx, "Moving card %s in deck %s to the trash", cardID, deckID)
+
+	// The card goes into the trash first so that it is never lost if the deck write fails
+	if err := app.dataStore.PutTrashItem(ctx, cards.TrashCard(card, time.Now())); err != nil {
+		app.showStoreError(w, r, err, "2002")
+		return
+	}
+	if err := app.dataStore.DeleteCard(ctx, deckID, cardID); err != nil {
+		app.showStoreError(w, r, err, "2002")
+		return
+	}
+	app.recordChange(r, cards.CardChange(cards.CHANGE_DELETED, &card, nil, actorID(r), time.Now()))
+
+	http.Redirect(w, r, "/deck/"+deckID, http.StatusSeeOther)
+}
+
+// deleteDeck moves a whole deck into the trash, as long as it has no sub-decks
+func (app *Application) deleteDeck(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	if !app.requireUser(w, r) {
+		return
+	}
+
+	r.ParseForm()
+	deckID := r.Form.Get("deck_id")
+
+	deck, err := app.dataStore.GetDeck(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	children, err := app.dataStore.GetChildDecks(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	if len(children) > 0 {
+		app.showError(w, r, "2004")
+		return
+	}
+
+	app.logs.Info(ctx, "Moving deck %s to the trash", deckID)
+
+	item := cards.TrashDeck(deck, time.Now())
+	if err := app.dataStore.PutTrashItem(ctx, item); err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	if err := app.dataStore.DeleteDeck(ctx, deckID); err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	app.recordChange(r, cards.DeckChange(cards.CHANGE_DELETED, &deck, nil, actorID(r), time.Now()))
+
+	http.Redirect(w, r, "/deck/"+item.DeckID+"/trash", http.StatusSeeOther)
+}
+
+// trashPage lists the deleted items for a deck, and restores or purges them
+func (app *Application) trashPage(w http.ResponseWriter, r *http.Request) {
+	ctx := requestContext(r)
+	deckID := mux.Vars(r)["id"]
+
+	if r.Method == "POST" {
+		if !app.requireUser(w, r) {
+			return
+		}
+		r.ParseForm()
+		item, err := app.dataStore.GetTrashItem(ctx, r.Form.Get("item"))
+		if err != nil {
+			app.showStoreError(w, r, err, "2005")
+			return
+		}
+		if item.DeckID != deckID {
+			app.showError(w, r, "2005")
+			return
+		}
+
+		switch r.Form.Get("action") {
+		case "restore":
+			app.restoreItem(w, r, item)
+		case "purge":
+			app.logs.Info(ctx, "Purging trash item %s from deck %s", item.ID, deckID)
+			if err := app.dataStore.DeleteTrashItem(ctx, item.ID); err != nil {
+				app.showStoreError(w, r, err, "2005")
+				return
+			}
+			http.Redirect(w, r, "/deck/"+deckID+"/trash", http.StatusSeeOther)
+		default:
+			app.showError(w, r, "1001")
+		}
+		return
+	}
+
+	deck, err := app.dataStore.GetDeck(ctx, deckID)
+	if err != nil && !errors.Is(err, platform.ErrNotFound) {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	items, err := app.dataStore.GetTrash(ctx, deckID)
+	if err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+
+	data := pageData{
+		Deck:  deck,
+		Trash: make([]cards.TrashItem, 0),
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		if item.IsExpired(now) {
+			app.logs.Info(ctx, "Purging expired trash item %s from deck %s", item.ID, deckID)
+			if err := app.dataStore.DeleteTrashItem(ctx, item.ID); err != nil {
+				app.logs.Error(ctx, "Failed to purge trash item %s, %v", item.ID, err)
+			}
+			continue
+		}
+		if data.Deck.ID != deckID && item.IsDeck() && item.ID == deckID {
+			data.Deck = *item.Deck
+			data.Message = "This deck is in the trash"
+		}
+		data.Trash = append(data.Trash, item)
+	}
+
+	if data.Deck.ID != deckID {
+		app.showError(w, r, "2001")
+		return
+	}
+
+	data.Title = data.Deck.Title + " - Trash"
+	app.showTemplatePage("trash", data, w)
+}
+
+// restoreItem puts a deleted card back in its deck, or a deleted deck back in the store
+func (app *Application) restoreItem(w http.ResponseWriter, r *http.Request, item cards.TrashItem) {
+	ctx := requestContext(r)
+
+	if item.IsDeck() {
+		deck := *item.Deck
+		if deck.ParentID != "" {
+			_, err := app.dataStore.GetDeck(ctx, deck.ParentID)
+			if errors.Is(err, platform.ErrNotFound) {
+				// The parent has been deleted since, so the deck comes back at the top level
+				deck.ParentID = ""
+			} else if err != nil {
+				app.showStoreError(w, r, err, "2001")
+				return
+			}
+		}
+		app.logs.Info(ctx, "Restoring deck %s from the trash", deck.ID)
+		if err := app.dataStore.PutDeck(ctx, deck.ID, deck); err != nil {
+			app.showStoreError(w, r, err, "2001")
+			return
+		}
+		restored := deck
+		restored.Version++
+		app.recordChange(r, cards.DeckChange(cards.CHANGE_RESTORED, nil, &restored, actorID(r), time.Now()))
+		if err := app.dataStore.DeleteTrashItem(ctx, item.ID); err != nil {
+			app.showStoreError(w, r, err, "2005")
+			return
+		}
+		http.Redirect(w, r, "/deck/"+deck.ID, http.StatusSeeOther)
+		return
+	}
+
+	if item.Card == nil {
+		app.showError(w, r, "2005")
+		return
+	}
+
+	app.logs.Info(ctx, "Restoring card %s to deck %s", item.ID, item.DeckID)
+	card := *item.Card
+	if current, err := app.dataStore.GetCard(ctx, item.DeckID, card.ID); err == nil {
+		// Carry on from the version in the deck, in case the card has been put back some other way
+		card.Version = current.Version
+	}
+	if err := app.dataStore.PutCard(ctx, item.DeckID, card); err != nil {
+		app.showStoreError(w, r, err, "2001")
+		return
+	}
+	app.recordChange(r, cards.CardChange(cards.CHANGE_RESTORED, nil, written(card, item.DeckID), actorID(r), time.Now()))
+	if err := app.dataStore.DeleteTrashItem(ctx, item.ID); err != nil {
+		app.showStoreError(w, r, err, "2005")
+		return
+	}
+
+	http.Redirect(w, r, "/deck/"+item.DeckID, http.StatusSeeOther)
+}
@@ -0,0 +1,90 @@
+package pgstore
+
+// A migration is a change to the Postgres schema. Its statements are run in a single transaction,
+// along with recording it in the schema_migrations table.
+type migration struct {
+	Description string
+	Statements  []string
+}
+
+// Every migration in the order they are applied. The version of each one is its position in the
+// list counting from 1, so new migrations are only ever added to the end.
+//
+// Titles and IDs use the "C" collation so that they sort by their bytes, the same as in other stores.
+var migrations = []migration{
+	{"Create decks, cards and author keys", []string{
+		`CREATE TABLE decks (
+			id         TEXT COLLATE "C" PRIMARY KEY,
+			title      TEXT COLLATE "C" NOT NULL,
+			reversible BOOLEAN NOT NULL DEFAULT false,
+			parent_id  TEXT COLLATE "C" NOT NULL DEFAULT '',
+			version    INTEGER NOT NULL DEFAULT 0,
+			created    TIMESTAMPTZ NOT NULL DEFAULT '-infinity'
+		)`,
+		`CREATE INDEX decks_parent ON decks (parent_id, title)`,
+		`CREATE TABLE cards (
+			deck_id      TEXT COLLATE "C" NOT NULL REFERENCES decks (id) ON DELETE CASCADE,
+			id           TEXT COLLATE "C" NOT NULL,
+			type         TEXT NOT NULL DEFAULT '',
+			question     TEXT NOT NULL DEFAULT '',
+			answer       TEXT NOT NULL DEFAULT '',
+			hint         TEXT NOT NULL DEFAULT '',
+			alternatives TEXT[] NOT NULL DEFAULT '{}',
+			choices      TEXT[] NOT NULL DEFAULT '{}',
+			reversible   BOOLEAN,
+			tags         TEXT[] NOT NULL DEFAULT '{}',
+			version      INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (deck_id, id)
+		)`,
+		`CREATE TABLE author_keys (
+			key  TEXT PRIMARY KEY,
+			role TEXT NOT NULL
+		)`,
+	}},
+	{"Create progress, sessions, trash and changes", []string{
+		`CREATE TABLE progress (
+			learner_id TEXT NOT NULL,
+			deck_id    TEXT NOT NULL,
+			reviews    JSONB,
+			PRIMARY KEY (learner_id, deck_id)
+		)`,
+		`CREATE TABLE sessions (
+			id   TEXT PRIMARY KEY,
+			data JSONB NOT NULL
+		)`,
+		`CREATE TABLE trash (
+			id      TEXT PRIMARY KEY,
+			deck_id TEXT NOT NULL,
+			deleted TIMESTAMPTZ NOT NULL,
+			data    JSONB NOT NULL
+		)`,
+		`CREATE INDEX trash_deck ON trash (deck_id, deleted)`,
+		`CREATE TABLE changes (
+			id      TEXT COLLATE "C" PRIMARY KEY,
+			deck_id TEXT NOT NULL,
+			card_id TEXT NOT NULL DEFAULT '',
+			data    JSONB NOT NULL
+		)`,
+		`CREATE INDEX changes_deck ON changes (deck_id, card_id, id)`,
+	}},
+	{"Add deck slugs", []string{
+		`ALTER TABLE decks ADD COLUMN slug TEXT NOT NULL DEFAULT ''`,
+		`CREATE TABLE slugs (
+			slug    TEXT PRIMARY KEY,
+			deck_id TEXT COLLATE "C" NOT NULL
+		)`,
+	}},
+	{"Add users and logins", []string{
+		`ALTER TABLE decks ADD COLUMN created_by TEXT NOT NULL DEFAULT ''`,
+		`CREATE TABLE users (
+			name TEXT PRIMARY KEY,
+			data JSONB NOT NULL
+		)`,
+		`CREATE TABLE logins (
+			id      TEXT PRIMARY KEY,
+			expires TIMESTAMPTZ NOT NULL,
+			data    JSONB NOT NULL
+		)`,
+		`CREATE INDEX logins_expires ON logins (expires)`,
+	}},
+}
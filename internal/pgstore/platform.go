@@ -0,0 +1,36 @@
+package pgstore
+
+import (
+	"context"
+	"os"
+
+	"flashcards/internal/platform"
+)
+
+// PostgresServerPlatform runs the application with its data in a Postgres database, which several
+// servers can share.
+type PostgresServerPlatform struct {
+	logs  platform.ConsoleLogger
+	store PostgresDataStore
+}
+
+func PostgresPlatform(ctx context.Context, url string) *PostgresServerPlatform {
+	p := PostgresServerPlatform{}
+	p.store = *NewPostgresDataStore(&p.logs, url)
+	return &p
+}
+
+func (platform *PostgresServerPlatform) Logger() platform.Logger {
+	return &platform.logs
+}
+
+func (platform *PostgresServerPlatform) DataStore() platform.DataStore {
+	return &platform.store
+}
+
+func (platform *PostgresServerPlatform) ListenAddress() string {
+	if port := os.Getenv("PORT"); port != "" {
+		return ":" + port
+	}
+	return "127.0.0.1:8080"
+}
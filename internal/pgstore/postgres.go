@@ -0,0 +1,782 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// PostgresDataStore keeps decks, cards and author keys in their own tables of a Postgres database,
+// with progress, sessions, the trash and changes stored as JSONB documents. Connections come from a
+// pool, sized with the pool_max_conns and related parameters of the connection URL.
+type PostgresDataStore struct {
+	Pool   *pgxpool.Pool
+	URL    string
+	Schema string // the schema holding the tables, or the connection's search path when empty
+	Err    error
+	logs   platform.Logger
+}
+
+func NewPostgresDataStore(logs platform.Logger, url string) *PostgresDataStore {
+	return &PostgresDataStore{URL: url, logs: logs}
+}
+
+func (store *PostgresDataStore) Summary() string {
+	if store.Pool == nil {
+		return "PostgresDataStore"
+	}
+	config := store.Pool.Config()
+	return fmt.Sprintf("PostgresDataStore(%s:%d/%s, %d connections)", config.ConnConfig.Host, config.ConnConfig.Port,
+		config.ConnConfig.Database, config.MaxConns)
+}
+
+// Init connects to the database and applies any migrations it does not have yet.
+func (store *PostgresDataStore) Init(ctx context.Context) {
+	var config *pgxpool.Config
+	if config, store.Err = pgxpool.ParseConfig(store.URL); store.Err != nil {
+		store.logs.Error(ctx, "Invalid Postgres connection URL: %v", store.Err)
+		return
+	}
+	if store.Schema != "" {
+		config.ConnConfig.RuntimeParams["search_path"] = store.Schema
+	}
+	if store.Pool, store.Err = pgxpool.NewWithConfig(ctx, config); store.Err != nil {
+		store.logs.Error(ctx, "Failed to connect to Postgres: %v", store.Err)
+		return
+	}
+	if store.Schema != "" {
+		_, store.Err = store.Pool.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{store.Schema}.Sanitize())
+		if store.Err != nil {
+			store.logs.Error(ctx, "Failed to create Postgres schema %s: %v", store.Schema, store.Err)
+			return
+		}
+	}
+	if store.Err = store.migrate(ctx); store.Err != nil {
+		store.logs.Error(ctx, "Failed to migrate Postgres schema: %v", store.Err)
+		return
+	}
+	store.logs.Info(ctx, "Initialised %s", store.Summary())
+}
+
+// MIGRATION_LOCK is the advisory lock held while migrating, so that servers starting at the same
+// time wait for each other rather than applying the same migration twice
+const MIGRATION_LOCK = 0x466C617368
+
+// migrate applies the migrations that the database does not have yet, all in one transaction
+func (store *PostgresDataStore) migrate(ctx context.Context) error {
+	tx, err := store.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", MIGRATION_LOCK); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied     TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var applied int
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&applied); err != nil {
+		return err
+	}
+	if applied > len(migrations) {
+		return fmt.Errorf("database is at schema version %d, newer than this server's %d", applied, len(migrations))
+	}
+	for n := applied; n < len(migrations); n++ {
+		store.logs.Info(ctx, "Applying Postgres migration %d: %s", n+1, migrations[n].Description)
+		for _, statement := range migrations[n].Statements {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("migration %d: %w", n+1, err)
+			}
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, description) VALUES ($1, $2)", n+1, migrations[n].Description)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (store *PostgresDataStore) Close() {
+	store.Pool.Close()
+}
+
+// storeError converts a Postgres error into one of the DataStore errors, keeping the original details
+func storeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %v", platform.ErrNotFound, err)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "23"): // integrity constraint violation
+			return fmt.Errorf("%w: %v", platform.ErrConflict, err)
+		case pgErr.Code == "40001", pgErr.Code == "40P01": // serialization failure, deadlock
+			return fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"), strings.HasPrefix(pgErr.Code, "57P"):
+			// connection problems, insufficient resources, and the server shutting down or starting up
+			return fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+		}
+		return err
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+	}
+	return err
+}
+
+// ready checks that Init managed to connect
+func (store *PostgresDataStore) ready() error {
+	if store.Pool == nil || store.Err != nil {
+		return fmt.Errorf("%w: %v", platform.ErrUnavailable, store.Err)
+	}
+	return nil
+}
+
+// timestamp is how times are kept, with -infinity for an unknown time so that it sorts first
+func timestamp(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		return pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// fromTimestamp reads a time kept by timestamp
+func fromTimestamp(ts pgtype.Timestamptz) time.Time {
+	if !ts.Valid || ts.InfinityModifier != pgtype.Finite {
+		return time.Time{}
+	}
+	return ts.Time.UTC()
+}
+
+func (store *PostgresDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	store.logs.Debug(ctx, "Fetching Postgres deck %s", id)
+
+	deck := cards.Deck{}
+	if err := store.ready(); err != nil {
+		return deck, err
+	}
+
+	var created pgtype.Timestamptz
+	row := store.Pool.QueryRow(ctx, "SELECT id, title, reversible, parent_id, version, created, slug, created_by FROM decks WHERE id = $1", id)
+	if err := row.Scan(&deck.ID, &deck.Title, &deck.Reversible, &deck.ParentID, &deck.Version, &created, &deck.Slug, &deck.CreatedBy); err != nil {
+		return cards.Deck{}, storeError(err)
+	}
+	deck.Created = fromTimestamp(created)
+
+	list, err := store.listCards(ctx, id)
+	if err != nil {
+		return cards.Deck{}, err
+	}
+	deck.Cards = make(map[string]cards.Card)
+	for _, card := range list {
+		deck.Cards[card.ID] = card
+	}
+	return deck, nil
+}
+
+const CARD_COLUMNS = "id, deck_id, type, question, answer, hint, alternatives, choices, reversible, tags, version"
+
+// listCards reads the cards of a deck, without checking that the deck exists
+func (store *PostgresDataStore) listCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	list := make([]cards.Card, 0)
+
+	rows, err := store.Pool.Query(ctx, "SELECT "+CARD_COLUMNS+" FROM cards WHERE deck_id = $1 ORDER BY id", deckID)
+	if err != nil {
+		return list, storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return list, storeError(err)
+		}
+		list = append(list, card)
+	}
+	return list, storeError(rows.Err())
+}
+
+// scanCard reads a card from a row of CARD_COLUMNS
+func scanCard(row pgx.Row) (cards.Card, error) {
+	var card cards.Card
+	err := row.Scan(&card.ID, &card.DeckID, &card.Type, &card.Question, &card.Answer, &card.Hint,
+		&card.Alternatives, &card.Choices, &card.Reversible, &card.Tags, &card.Version)
+	return card, err
+}
+
+// textList is a list for a TEXT[] column, using an empty list rather than null
+func textList(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+const WRITE_CARD = `INSERT INTO cards (deck_id, id, type, question, answer, hint, alternatives, choices, reversible, tags, version)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT (deck_id, id) DO UPDATE SET type = excluded.type, question = excluded.question, answer = excluded.answer,
+		hint = excluded.hint, alternatives = excluded.alternatives, choices = excluded.choices,
+		reversible = excluded.reversible, tags = excluded.tags, version = excluded.version`
+
+// writeCardArgs are the arguments of WRITE_CARD for the card, with its version incremented
+func writeCardArgs(deckID string, card cards.Card) []any {
+	return []any{deckID, card.ID, card.Type, card.Question, card.Answer, card.Hint,
+		textList(card.Alternatives), textList(card.Choices), card.Reversible, textList(card.Tags), card.Version + 1}
+}
+
+// PutDeck writes the deck and the cards in it in a single transaction, as long as the stored deck
+// is still at the version that was read. The cards are sent to the database in one batch.
+func (store *PostgresDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	store.logs.Info(ctx, "Writing Postgres deck %s at version %d", id, deck.Version)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	tx, err := store.Pool.Begin(ctx)
+	if err != nil {
+		return storeError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the row makes anyone else writing the deck wait until this transaction is done
+	var current int
+	err = tx.QueryRow(ctx, "SELECT version FROM decks WHERE id = $1 FOR UPDATE", id).Scan(&current)
+	exists := err == nil
+	if exists && current != deck.Version {
+		return fmt.Errorf("deck %s is at version %d not %d, %w", id, current, deck.Version, platform.ErrConflict)
+	} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return storeError(err)
+	}
+
+	batch := &pgx.Batch{}
+	if exists {
+		// The version check is repeated in the update, and the created time and creator are kept
+		batch.Queue(`UPDATE decks SET title = $2, reversible = $3, parent_id = $4, version = $5, slug = $6
+			WHERE id = $1 AND version = $7`,
+			id, deck.Title, deck.Reversible, deck.ParentID, deck.Version+1, deck.Slug, deck.Version).Exec(func(ct pgconn.CommandTag) error {
+			if ct.RowsAffected() == 0 {
+				return fmt.Errorf("deck %s changed while writing, %w", id, platform.ErrConflict)
+			}
+			return nil
+		})
+	} else {
+		// No row was there to lock, so a deck created at the same time makes this insert fail with a
+		// unique violation rather than being overwritten
+		created := deck.Created
+		if created.IsZero() {
+			created = time.Now()
+		}
+		batch.Queue(`INSERT INTO decks (id, title, reversible, parent_id, version, created, slug, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			id, deck.Title, deck.Reversible, deck.ParentID, deck.Version+1, created, deck.Slug, deck.CreatedBy)
+	}
+	for cardID, card := range deck.Cards {
+		card.ID = cardID
+		batch.Queue(WRITE_CARD, writeCardArgs(id, card)...)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return storeError(err)
+	}
+
+	return storeError(tx.Commit(ctx))
+}
+
+func (store *PostgresDataStore) GetCard(ctx context.Context, deckID string, cardID string) (cards.Card, error) {
+	store.logs.Debug(ctx, "Fetching Postgres card %s in deck %s", cardID, deckID)
+
+	if err := store.ready(); err != nil {
+		return cards.Card{}, err
+	}
+
+	row := store.Pool.QueryRow(ctx, "SELECT "+CARD_COLUMNS+" FROM cards WHERE deck_id = $1 AND id = $2", deckID, cardID)
+	card, err := scanCard(row)
+	if err != nil {
+		return cards.Card{}, storeError(err)
+	}
+	return card, nil
+}
+
+// PutCard writes a single card, as long as the stored card is still at the version that was read.
+func (store *PostgresDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	store.logs.Info(ctx, "Writing Postgres card %s in deck %s at version %d", card.ID, deckID, card.Version)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	tx, err := store.Pool.Begin(ctx)
+	if err != nil {
+		return storeError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A shared lock on the deck stops it being deleted while the card is written
+	var exists int
+	err = tx.QueryRow(ctx, "SELECT 1 FROM decks WHERE id = $1 FOR SHARE", deckID).Scan(&exists)
+	if err != nil {
+		return storeError(err)
+	}
+
+	var current int
+	err = tx.QueryRow(ctx, "SELECT version FROM cards WHERE deck_id = $1 AND id = $2 FOR UPDATE", deckID, card.ID).Scan(&current)
+	if err == nil && current != card.Version {
+		return fmt.Errorf("card %s is at version %d not %d, %w", card.ID, current, card.Version, platform.ErrConflict)
+	} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return storeError(err)
+	}
+
+	if _, err := tx.Exec(ctx, WRITE_CARD, writeCardArgs(deckID, card)...); err != nil {
+		return storeError(err)
+	}
+	return storeError(tx.Commit(ctx))
+}
+
+func (store *PostgresDataStore) DeleteCard(ctx context.Context, deckID string, cardID string) error {
+	store.logs.Info(ctx, "Deleting Postgres card %s in deck %s", cardID, deckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	result, err := store.Pool.Exec(ctx, "DELETE FROM cards WHERE deck_id = $1 AND id = $2", deckID, cardID)
+	if err != nil {
+		return storeError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("card %s in deck %s %w", cardID, deckID, platform.ErrNotFound)
+	}
+	return nil
+}
+
+func (store *PostgresDataStore) ListCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	store.logs.Debug(ctx, "Listing Postgres cards in deck %s", deckID)
+
+	if err := store.ready(); err != nil {
+		return []cards.Card{}, err
+	}
+
+	var exists int
+	err := store.Pool.QueryRow(ctx, "SELECT 1 FROM decks WHERE id = $1", deckID).Scan(&exists)
+	if err != nil {
+		return []cards.Card{}, storeError(err)
+	}
+	return store.listCards(ctx, deckID)
+}
+
+func (store *PostgresDataStore) GetChildDecks(ctx context.Context, parentID string) ([]cards.Deck, error) {
+	store.logs.Debug(ctx, "Fetching Postgres child decks of %s", parentID)
+
+	children := make([]cards.Deck, 0)
+	if err := store.ready(); err != nil {
+		return children, err
+	}
+
+	rows, err := store.Pool.Query(ctx, "SELECT id FROM decks WHERE parent_id = $1 ORDER BY title", parentID)
+	if err != nil {
+		return children, storeError(err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return children, storeError(err)
+	}
+
+	for _, id := range ids {
+		deck, err := store.GetDeck(ctx, id)
+		if err != nil {
+			return children, err
+		}
+		children = append(children, deck)
+	}
+	return children, nil
+}
+
+// The column each order of ListDecks sorts on
+var deckSortColumns = map[string]string{
+	platform.SORT_BY_TITLE:   "title",
+	platform.SORT_BY_CREATED: "created",
+	platform.SORT_BY_CARDS:   "card_count",
+}
+
+// ListDecks pages through the decks with a query that starts after the cursor's sort value and ID.
+func (store *PostgresDataStore) ListDecks(ctx context.Context, query platform.DeckQuery) (platform.DeckPage, error) {
+	page := platform.DeckPage{Decks: make([]platform.DeckSummary, 0)}
+	if err := store.ready(); err != nil {
+		return page, err
+	}
+	if err := query.Check(); err != nil {
+		return page, err
+	}
+	after, paged, err := query.DecodeCursor()
+	if err != nil {
+		return page, err
+	}
+
+	column := deckSortColumns[query.Sort]
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	statement := `SELECT id, title, parent_id, created, card_count FROM (
+			SELECT d.id, d.title, d.parent_id, d.created,
+				(SELECT COUNT(*) FROM cards c WHERE c.deck_id = d.id) AS card_count
+			FROM decks d
+		) AS summaries WHERE strpos(lower(title), lower($1)) > 0`
+	args := []any{query.Title}
+	if query.TopLevel {
+		statement += " AND parent_id = ''"
+	}
+	if paged {
+		var value any
+		switch query.Sort {
+		case platform.SORT_BY_CREATED:
+			value = timestamp(after.Created)
+		case platform.SORT_BY_CARDS:
+			value = after.CardCount
+		default:
+			value = after.Title
+		}
+		statement += fmt.Sprintf(" AND (%s %s $2 OR (%s = $2 AND id %s $3))", column, comparison, column, comparison)
+		args = append(args, value, after.ID)
+	}
+	statement += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", column, direction, direction, len(args)+1)
+	args = append(args, query.Limit+1)
+
+	rows, err := store.Pool.Query(ctx, statement, args...)
+	if err != nil {
+		return page, storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deck platform.DeckSummary
+		var created pgtype.Timestamptz
+		if err := rows.Scan(&deck.ID, &deck.Title, &deck.ParentID, &created, &deck.CardCount); err != nil {
+			return page, storeError(err)
+		}
+		deck.Created = fromTimestamp(created)
+		page.Decks = append(page.Decks, deck)
+	}
+	if len(page.Decks) > query.Limit {
+		page.Decks = page.Decks[:query.Limit]
+		page.NextCursor = query.EncodeCursor(page.Decks[query.Limit-1])
+	}
+	return page, storeError(rows.Err())
+}
+
+func (store *PostgresDataStore) DeleteDeck(ctx context.Context, id string) error {
+	store.logs.Info(ctx, "Deleting Postgres deck %s", id)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	result, err := store.Pool.Exec(ctx, "DELETE FROM decks WHERE id = $1", id)
+	if err != nil {
+		return storeError(err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("deck %s %w", id, platform.ErrNotFound)
+	}
+	return nil
+}
+
+func (store *PostgresDataStore) IsEmpty() bool {
+	if store.ready() != nil {
+		return false
+	}
+	var exists bool
+	store.Pool.QueryRow(context.Background(), "SELECT EXISTS (SELECT 1 FROM decks)").Scan(&exists)
+	return !exists
+}
+
+func (store *PostgresDataStore) IsValidAuthor(key string) bool {
+	if store.ready() != nil {
+		return false
+	}
+	var role string
+	err := store.Pool.QueryRow(context.Background(), "SELECT role FROM author_keys WHERE key = $1", strings.TrimSpace(key)).Scan(&role)
+	if err != nil {
+		store.logs.Info(context.Background(), "Author key not found")
+		return false
+	}
+	if role != "author" {
+		store.logs.Info(context.Background(), "Key does not have author role")
+		return false
+	}
+	return true
+}
+
+func (store *PostgresDataStore) GetAuthorKeys(ctx context.Context) (map[string]string, error) {
+	keys := make(map[string]string)
+	if err := store.ready(); err != nil {
+		return keys, err
+	}
+
+	rows, err := store.Pool.Query(ctx, "SELECT key, role FROM author_keys")
+	if err != nil {
+		return keys, storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, role string
+		if err := rows.Scan(&key, &role); err != nil {
+			return keys, storeError(err)
+		}
+		keys[key] = role
+	}
+	return keys, storeError(rows.Err())
+}
+
+// PutAuthorKey adds or updates a key that lets people create decks.
+func (store *PostgresDataStore) PutAuthorKey(ctx context.Context, key string, role string) error {
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, "INSERT INTO author_keys (key, role) VALUES ($1, $2) ON CONFLICT (key) DO UPDATE SET role = excluded.role",
+		strings.TrimSpace(key), role)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) GetProgress(ctx context.Context, learnerID string, deckID string) (cards.Progress, error) {
+	progress := cards.Progress{LearnerID: learnerID, DeckID: deckID}
+	if err := store.ready(); err != nil {
+		return progress, err
+	}
+
+	err := store.Pool.QueryRow(ctx, "SELECT reviews FROM progress WHERE learner_id = $1 AND deck_id = $2", learnerID, deckID).
+		Scan(&progress.Reviews)
+	if errors.Is(err, pgx.ErrNoRows) {
+		store.logs.Debug(ctx, "No progress found for learner %s on deck %s", learnerID, deckID)
+		return progress, nil
+	}
+	return progress, storeError(err)
+}
+
+func (store *PostgresDataStore) PutProgress(ctx context.Context, progress cards.Progress) error {
+	store.logs.Debug(ctx, "Writing progress for learner %s on deck %s", progress.LearnerID, progress.DeckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, `INSERT INTO progress (learner_id, deck_id, reviews) VALUES ($1, $2, $3)
+		ON CONFLICT (learner_id, deck_id) DO UPDATE SET reviews = excluded.reviews`,
+		progress.LearnerID, progress.DeckID, progress.Reviews)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) GetSession(ctx context.Context, id string) (cards.Session, error) {
+	var session cards.Session
+	if err := store.ready(); err != nil {
+		return session, err
+	}
+
+	err := store.Pool.QueryRow(ctx, "SELECT data FROM sessions WHERE id = $1", id).Scan(&session)
+	return session, storeError(err)
+}
+
+func (store *PostgresDataStore) PutSession(ctx context.Context, session cards.Session) error {
+	store.logs.Debug(ctx, "Writing study session %s", session.ID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, "INSERT INTO sessions (id, data) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET data = excluded.data",
+		session.ID, session)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) GetTrash(ctx context.Context, deckID string) ([]cards.TrashItem, error) {
+	items := make([]cards.TrashItem, 0)
+	if err := store.ready(); err != nil {
+		return items, err
+	}
+
+	rows, err := store.Pool.Query(ctx, "SELECT data FROM trash WHERE deck_id = $1 ORDER BY deleted DESC", deckID)
+	if err != nil {
+		return items, storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item cards.TrashItem
+		if err := rows.Scan(&item); err != nil {
+			return items, storeError(err)
+		}
+		items = append(items, item)
+	}
+	return items, storeError(rows.Err())
+}
+
+func (store *PostgresDataStore) GetTrashItem(ctx context.Context, id string) (cards.TrashItem, error) {
+	var item cards.TrashItem
+	if err := store.ready(); err != nil {
+		return item, err
+	}
+
+	err := store.Pool.QueryRow(ctx, "SELECT data FROM trash WHERE id = $1", id).Scan(&item)
+	return item, storeError(err)
+}
+
+func (store *PostgresDataStore) PutTrashItem(ctx context.Context, item cards.TrashItem) error {
+	store.logs.Debug(ctx, "Writing trash item %s", item.ID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, `INSERT INTO trash (id, deck_id, deleted, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET deck_id = excluded.deck_id, deleted = excluded.deleted, data = excluded.data`,
+		item.ID, item.DeckID, item.Deleted, item)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) DeleteTrashItem(ctx context.Context, id string) error {
+	store.logs.Debug(ctx, "Deleting trash item %s", id)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, "DELETE FROM trash WHERE id = $1", id)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) GetChanges(ctx context.Context, deckID string, cardID string) ([]cards.Change, error) {
+	changes := make([]cards.Change, 0)
+	if err := store.ready(); err != nil {
+		return changes, err
+	}
+
+	query := "SELECT data FROM changes WHERE deck_id = $1 ORDER BY id DESC"
+	args := []any{deckID}
+	if cardID != "" {
+		query = "SELECT data FROM changes WHERE deck_id = $1 AND card_id = $2 ORDER BY id DESC"
+		args = append(args, cardID)
+	}
+	rows, err := store.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return changes, storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change cards.Change
+		if err := rows.Scan(&change); err != nil {
+			return changes, storeError(err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, storeError(rows.Err())
+}
+
+func (store *PostgresDataStore) PutChange(ctx context.Context, change cards.Change) error {
+	store.logs.Debug(ctx, "Writing change %s to deck %s", change.ID, change.DeckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, `INSERT INTO changes (id, deck_id, card_id, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET deck_id = excluded.deck_id, card_id = excluded.card_id, data = excluded.data`,
+		change.ID, change.DeckID, change.CardID, change)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) GetSlug(ctx context.Context, slug string) (string, error) {
+	if err := store.ready(); err != nil {
+		return "", err
+	}
+	var deckID string
+	err := store.Pool.QueryRow(ctx, "SELECT deck_id FROM slugs WHERE slug = $1", slug).Scan(&deckID)
+	return deckID, storeError(err)
+}
+
+// PutSlug claims a slug for a deck, unless another deck has already claimed it. Whichever claim
+// reaches the database first wins, and the other sees the deck that won.
+func (store *PostgresDataStore) PutSlug(ctx context.Context, slug string, deckID string) error {
+	store.logs.Info(ctx, "Claiming slug %s for deck %s", slug, deckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, "INSERT INTO slugs (slug, deck_id) VALUES ($1, $2) ON CONFLICT (slug) DO NOTHING", slug, deckID)
+	if err != nil {
+		return storeError(err)
+	}
+	current, err := store.GetSlug(ctx, slug)
+	if err == nil && current != deckID {
+		return fmt.Errorf("slug %s belongs to deck %s, %w", slug, current, platform.ErrConflict)
+	}
+	return err
+}
+
+func (store *PostgresDataStore) GetUser(ctx context.Context, name string) (cards.User, error) {
+	var user cards.User
+	if err := store.ready(); err != nil {
+		return user, err
+	}
+
+	err := store.Pool.QueryRow(ctx, "SELECT data FROM users WHERE name = $1", name).Scan(&user)
+	return user, storeError(err)
+}
+
+// PutUser adds a new user, the primary key refusing a name that is already registered.
+func (store *PostgresDataStore) PutUser(ctx context.Context, user cards.User) error {
+	store.logs.Info(ctx, "Adding user %s", user.Name)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, "INSERT INTO users (name, data) VALUES ($1, $2)", user.Name, user)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) GetLogin(ctx context.Context, id string) (cards.Login, error) {
+	var login cards.Login
+	if err := store.ready(); err != nil {
+		return login, err
+	}
+
+	err := store.Pool.QueryRow(ctx, "SELECT data FROM logins WHERE id = $1", id).Scan(&login)
+	return login, storeError(err)
+}
+
+func (store *PostgresDataStore) PutLogin(ctx context.Context, login cards.Login) error {
+	store.logs.Debug(ctx, "Writing login for user %s", login.UserName)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, `INSERT INTO logins (id, expires, data) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET expires = excluded.expires, data = excluded.data`,
+		login.ID, login.Expires, login)
+	return storeError(err)
+}
+
+func (store *PostgresDataStore) DeleteLogin(ctx context.Context, id string) error {
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.Pool.Exec(ctx, "DELETE FROM logins WHERE id = $1", id)
+	return storeError(err)
+}
@@ -0,0 +1,131 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+	"flashcards/internal/test"
+)
+
+func TestStoreErrors(t *testing.T) {
+	cases := map[string]error{
+		"23505": platform.ErrConflict,    // unique violation
+		"23503": platform.ErrConflict,    // foreign key violation
+		"40001": platform.ErrUnavailable, // serialization failure
+		"40P01": platform.ErrUnavailable, // deadlock
+		"08006": platform.ErrUnavailable, // connection failure
+		"53300": platform.ErrUnavailable, // too many connections
+		"57P01": platform.ErrUnavailable, // admin shutdown
+	}
+	for code, expected := range cases {
+		err := storeError(&pgconn.PgError{Code: code})
+		if !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %s, got %v", expected, code, err)
+		}
+	}
+
+	if err := storeError(fmt.Errorf("scanning: %w", pgx.ErrNoRows)); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found for no rows, got %v", err)
+	}
+	err := storeError(&pgconn.PgError{Code: "42P01"}) // undefined table
+	if errors.Is(err, platform.ErrNotFound) || errors.Is(err, platform.ErrConflict) || errors.Is(err, platform.ErrUnavailable) {
+		t.Errorf("Unexpected error mapping for undefined table: %v", err)
+	}
+}
+
+func TestNotConnected(t *testing.T) {
+	store := NewPostgresDataStore(&test.LogRecorder{}, "not a url")
+	store.Init(context.Background())
+	if store.Err == nil {
+		t.Fatal("Expected an error for a bad connection URL")
+	}
+	if _, err := store.GetDeck(context.Background(), "D1"); !errors.Is(err, platform.ErrUnavailable) {
+		t.Errorf("Expected unavailable error, got %v", err)
+	}
+}
+
+// testURL is the database that tests needing Postgres run against, such as
+// postgres://postgres@localhost/flashcards_test, which they skip without
+func testURL(t *testing.T) string {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TEST_URL is not set")
+	}
+	return url
+}
+
+// testStore connects to the test database, with the tables in a schema of their own that is
+// dropped when the test ends
+func testStore(t *testing.T) *PostgresDataStore {
+	ctx := context.Background()
+	store := NewPostgresDataStore(&test.LogRecorder{}, testURL(t))
+	store.Schema = fmt.Sprintf("conformance_%d", rand.Int63())
+	store.Init(ctx)
+	if store.Err != nil {
+		t.Fatalf("Failed to initialise store: %v", store.Err)
+	}
+	t.Cleanup(func() {
+		store.Pool.Exec(ctx, "DROP SCHEMA "+pgx.Identifier{store.Schema}.Sanitize()+" CASCADE")
+		store.Close()
+	})
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	testURL(t)
+	test.RunDataStoreConformance(t, func(t *testing.T) platform.DataStore {
+		return testStore(t)
+	})
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	// Migrating again, as another server starting would, changes nothing
+	if err := store.migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate again: %v", err)
+	}
+	var count, version int
+	err := store.Pool.QueryRow(ctx, "SELECT COUNT(*), MAX(version) FROM schema_migrations").Scan(&count, &version)
+	if err != nil || count != len(migrations) || version != len(migrations) {
+		t.Errorf("Expected %d migrations applied, got %d up to %d, %v", len(migrations), count, version, err)
+	}
+
+	store.Pool.Exec(ctx, "INSERT INTO schema_migrations (version, description) VALUES ($1, 'From a newer server')", len(migrations)+1)
+	if err := store.migrate(ctx); err == nil {
+		t.Error("Expected an error for a database migrated by a newer server")
+	}
+}
+
+func TestDeckWriteIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	deck := cards.Deck{ID: "D1", Title: "First"}
+	deck.AddCard(cards.Card{ID: "C1", Question: "Q1"})
+	store.PutDeck(ctx, "D1", deck)
+
+	// Postgres text cannot hold a zero byte, so writing C2 fails and the other changes must be undone
+	deck, _ = store.GetDeck(ctx, "D1")
+	deck.Title = "Changed"
+	deck.Cards["C1"] = cards.Card{ID: "C1", Question: "Changed", Version: deck.Cards["C1"].Version}
+	deck.Cards["C2"] = cards.Card{ID: "C2", Question: "Bad\x00"}
+	if err := store.PutDeck(ctx, "D1", deck); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+
+	read, err := store.GetDeck(ctx, "D1")
+	if err != nil || read.Title != "First" || read.Version != 1 || len(read.Cards) != 1 || read.Cards["C1"].Question != "Q1" {
+		t.Errorf("Failed write partly applied: %+v, %v", read, err)
+	}
+}
@@ -0,0 +1,202 @@
+package platform
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"flashcards/internal/cards"
+)
+
+// How many deck lookups there are between each log of the cache counters
+const CACHE_STATS_INTERVAL = 100
+
+// CachingDataStore keeps recently read decks in memory in front of another DataStore. Decks stay
+// cached until their TTL runs out or they are pushed out by newer ones, and are dropped whenever
+// this instance writes them, so they can only be stale after a write through some other instance.
+// Everything other than reading decks goes straight to the wrapped store.
+type CachingDataStore struct {
+	DataStore
+	logs   Logger
+	ttl    time.Duration
+	size   int
+	now    func() time.Time
+	mu     sync.Mutex
+	decks  map[string]*list.Element
+	recent *list.List // of *cachedDeck, most recently used first
+	writes int        // so that a deck read before a write finishes is not cached
+	stats  CacheStats
+}
+
+type cachedDeck struct {
+	id      string // the ID the deck was looked up by, which the deck's own ID might not match
+	deck    cards.Deck
+	expires time.Time
+}
+
+// CacheStats counts how well the cache is doing, for tuning its TTL and size
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+}
+
+func NewCachingDataStore(store DataStore, logs Logger, ttl time.Duration, size int) *CachingDataStore {
+	return &CachingDataStore{
+		DataStore: store,
+		logs:      logs,
+		ttl:       ttl,
+		size:      size,
+		now:       time.Now,
+		decks:     make(map[string]*list.Element),
+		recent:    list.New(),
+	}
+}
+
+func (cache *CachingDataStore) Summary() string {
+	return fmt.Sprintf("CachingDataStore(%s,%v,%d)", cache.DataStore.Summary(), cache.ttl, cache.size)
+}
+
+// Stats returns the cache counters so far
+func (cache *CachingDataStore) Stats() CacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.stats
+}
+
+func (cache *CachingDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	deck, ok, writes := cache.lookup(ctx, id)
+	if ok {
+		return deck, nil
+	}
+	deck, err := cache.DataStore.GetDeck(ctx, id)
+	if err == nil {
+		cache.add(id, deck, writes)
+	}
+	return deck, err
+}
+
+// lookup finds an unexpired deck in the cache, counting the hit or miss. It also returns the
+// number of writes so far, for adding the deck after a miss.
+func (cache *CachingDataStore) lookup(ctx context.Context, id string) (cards.Deck, bool, int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	defer func() {
+		if (cache.stats.Hits+cache.stats.Misses)%CACHE_STATS_INTERVAL == 0 {
+			cache.logStats(ctx)
+		}
+	}()
+
+	element, ok := cache.decks[id]
+	if !ok {
+		cache.stats.Misses++
+		return cards.Deck{}, false, cache.writes
+	}
+	entry := element.Value.(*cachedDeck)
+	if cache.now().After(entry.expires) {
+		cache.remove(id)
+		cache.stats.Misses++
+		return cards.Deck{}, false, cache.writes
+	}
+
+	cache.recent.MoveToFront(element)
+	cache.stats.Hits++
+	return copyDeck(entry.deck), true, cache.writes
+}
+
+func (cache *CachingDataStore) logStats(ctx context.Context) {
+	lookups := cache.stats.Hits + cache.stats.Misses
+	cache.logs.Info(ctx, "Deck cache: %d hits, %d misses (%d%% hit rate), %d evictions, %d of %d decks cached",
+		cache.stats.Hits, cache.stats.Misses, cache.stats.Hits*100/lookups, cache.stats.Evictions, len(cache.decks), cache.size)
+}
+
+// add caches a deck, evicting the least recently used decks to stay within the size. The deck is
+// not cached if anything has been written since it was looked up, as it might be out of date.
+func (cache *CachingDataStore) add(id string, deck cards.Deck, writes int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if writes != cache.writes {
+		return
+	}
+
+	cache.remove(id)
+	entry := &cachedDeck{id: id, deck: copyDeck(deck), expires: cache.now().Add(cache.ttl)}
+	cache.decks[id] = cache.recent.PushFront(entry)
+
+	for cache.recent.Len() > cache.size {
+		oldest := cache.recent.Back()
+		cache.recent.Remove(oldest)
+		delete(cache.decks, oldest.Value.(*cachedDeck).id)
+		cache.stats.Evictions++
+	}
+}
+
+// remove drops a deck from the cache, the caller must hold the lock
+func (cache *CachingDataStore) remove(id string) {
+	if element, ok := cache.decks[id]; ok {
+		cache.recent.Remove(element)
+		delete(cache.decks, id)
+	}
+}
+
+// invalidate drops a deck that is being written, whether or not the write works, as a failed
+// write can mean the cached copy was out of date
+func (cache *CachingDataStore) invalidate(id string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.writes++
+	cache.remove(id)
+}
+
+// copyDeck gives each caller its own card map, so that changing a deck does not change the cache
+func copyDeck(deck cards.Deck) cards.Deck {
+	if deck.Cards != nil {
+		cardMap := make(map[string]cards.Card, len(deck.Cards))
+		for id, card := range deck.Cards {
+			cardMap[id] = card
+		}
+		deck.Cards = cardMap
+	}
+	return deck
+}
+
+func (cache *CachingDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	defer cache.invalidate(id)
+	return cache.DataStore.PutDeck(ctx, id, deck)
+}
+
+func (cache *CachingDataStore) DeleteDeck(ctx context.Context, id string) error {
+	defer cache.invalidate(id)
+	return cache.DataStore.DeleteDeck(ctx, id)
+}
+
+func (cache *CachingDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	defer cache.invalidate(deckID)
+	return cache.DataStore.PutCard(ctx, deckID, card)
+}
+
+func (cache *CachingDataStore) DeleteCard(ctx context.Context, deckID string, cardID string) error {
+	defer cache.invalidate(deckID)
+	return cache.DataStore.DeleteCard(ctx, deckID, cardID)
+}
+
+// CachingPlatform is another platform with a deck cache in front of its data store
+type CachingPlatform struct {
+	Platform
+	store *CachingDataStore
+}
+
+func NewCachingPlatform(platform Platform, ttl time.Duration, size int) *CachingPlatform {
+	return &CachingPlatform{
+		Platform: platform,
+		store:    NewCachingDataStore(platform.DataStore(), platform.Logger(), ttl, size),
+	}
+}
+
+func (platform *CachingPlatform) DataStore() DataStore {
+	return platform.store
+}
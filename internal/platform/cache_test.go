@@ -0,0 +1,134 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"flashcards/internal/cards"
+)
+
+// countingDataStore counts how many decks are read from it
+type countingDataStore struct {
+	TestDataStore
+	reads int
+}
+
+func (store *countingDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	store.reads++
+	return store.TestDataStore.GetDeck(ctx, id)
+}
+
+func testCache(size int) (*CachingDataStore, *countingDataStore, *time.Time) {
+	store := &countingDataStore{}
+	store.Init(context.Background())
+	cache := NewCachingDataStore(store, &ConsoleLogger{}, time.Minute, size)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, store, &now
+}
+
+func TestCacheHitsAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache, store, now := testCache(10)
+	cache.PutDeck(ctx, "D1", cards.Deck{ID: "D1", Title: "Deck"})
+
+	cache.GetDeck(ctx, "D1")
+	deck, err := cache.GetDeck(ctx, "D1")
+	if err != nil || deck.Title != "Deck" {
+		t.Errorf("Unexpected deck %+v, %v", deck, err)
+	}
+	if store.reads != 1 {
+		t.Errorf("Expected 1 read from the store, got %d", store.reads)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	cache.GetDeck(ctx, "D1")
+	if store.reads != 2 {
+		t.Errorf("Expected expired deck to be read again, got %d reads", store.reads)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	ctx := context.Background()
+	cache, _, _ := testCache(10)
+	cache.PutDeck(ctx, "D1", cards.Deck{ID: "D1", Title: "Deck"})
+
+	deck, _ := cache.GetDeck(ctx, "D1")
+	deck.Title = "Renamed"
+	cache.PutDeck(ctx, "D1", deck)
+	if deck, _ := cache.GetDeck(ctx, "D1"); deck.Title != "Renamed" {
+		t.Errorf("Cached deck not updated after PutDeck: %s", deck.Title)
+	}
+
+	cache.PutCard(ctx, "D1", cards.Card{ID: "C1", Question: "Q"})
+	if deck, _ := cache.GetDeck(ctx, "D1"); len(deck.Cards) != 1 {
+		t.Errorf("Cached deck not updated after PutCard: %+v", deck.Cards)
+	}
+
+	cache.DeleteCard(ctx, "D1", "C1")
+	if deck, _ := cache.GetDeck(ctx, "D1"); len(deck.Cards) != 0 {
+		t.Errorf("Cached deck not updated after DeleteCard: %+v", deck.Cards)
+	}
+
+	cache.DeleteDeck(ctx, "D1")
+	if _, err := cache.GetDeck(ctx, "D1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error after DeleteDeck, got %v", err)
+	}
+}
+
+func TestCacheSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	cache, store, _ := testCache(2)
+	for _, id := range []string{"D1", "D2", "D3"} {
+		cache.PutDeck(ctx, id, cards.Deck{ID: id})
+	}
+
+	cache.GetDeck(ctx, "D1")
+	cache.GetDeck(ctx, "D2")
+	cache.GetDeck(ctx, "D1")
+	cache.GetDeck(ctx, "D3") // pushes out D2, which was used least recently
+	store.reads = 0
+
+	cache.GetDeck(ctx, "D1")
+	cache.GetDeck(ctx, "D2")
+	if store.reads != 1 {
+		t.Errorf("Expected only the evicted deck to be read again, got %d reads", store.reads)
+	}
+	if stats := cache.Stats(); stats.Evictions != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheEvictsByLookupID(t *testing.T) {
+	ctx := context.Background()
+	cache, _, _ := testCache(1)
+	cache.PutDeck(ctx, "K1", cards.Deck{ID: "D1"})
+	cache.PutDeck(ctx, "K2", cards.Deck{ID: "D2"})
+
+	cache.GetDeck(ctx, "K1")
+	cache.GetDeck(ctx, "K2")
+	if len(cache.decks) != 1 || cache.recent.Len() != 1 {
+		t.Errorf("Evicted deck left in the cache: %d entries, %d recent", len(cache.decks), cache.recent.Len())
+	}
+}
+
+func TestCachedDeckCopies(t *testing.T) {
+	ctx := context.Background()
+	cache, _, _ := testCache(10)
+	deck := cards.Deck{ID: "D1"}
+	deck.AddCard(cards.Card{ID: "C1"})
+	cache.PutDeck(ctx, "D1", deck)
+
+	first, _ := cache.GetDeck(ctx, "D1")
+	delete(first.Cards, "C1")
+
+	if second, _ := cache.GetDeck(ctx, "D1"); len(second.Cards) != 1 {
+		t.Errorf("Changing a deck changed the cached copy: %+v", second.Cards)
+	}
+}
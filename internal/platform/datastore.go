@@ -2,21 +2,97 @@ package platform
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"flashcards/internal/cards"
 )
 
+// DataStore persists decks, learner progress, study sessions, the trash and the history of changes
+// to decks. Operations fail with an error wrapping ErrNotFound, ErrConflict or ErrUnavailable where
+// one of those applies.
+//
+// Decks are read along with all of their cards, but each card is stored on its own so that changing
+// one card does not rewrite the whole deck. PutDeck writes the deck's own settings along with any
+// cards in deck.Cards, leaving other stored cards alone, and DeleteDeck removes the deck's cards too.
+//
+// PutDeck only replaces a deck if the stored deck still has the Version that was read, failing with
+// ErrConflict otherwise, and stores the deck with its Version incremented. A new deck is stored with
+// Created set to the current time unless it already has one, and Created never changes after that,
+// nor does CreatedBy. PutCard does the same with the card's Version, and fails with ErrNotFound if
+// the deck does not exist.
+//
+// PutSlug claims a slug for a deck, failing with ErrConflict if a different deck already has it.
+// Claims are never released, so links using a slug keep working even after the deck takes another.
+//
+// PutUser only adds new users, failing with ErrConflict if the name is already registered. Logins
+// are found by their ID, and DeleteLogin succeeds if the login has already gone.
 type DataStore interface {
 	Summary() string
 	Init(ctx context.Context)
-	GetDeck(ctx context.Context, id string) cards.Deck
-	PutDeck(ctx context.Context, id string, deck cards.Deck)
+	GetDeck(ctx context.Context, id string) (cards.Deck, error)
+	PutDeck(ctx context.Context, id string, deck cards.Deck) error
+	GetChildDecks(ctx context.Context, parentID string) ([]cards.Deck, error)
+	ListDecks(ctx context.Context, query DeckQuery) (DeckPage, error)
+	DeleteDeck(ctx context.Context, id string) error
+	GetCard(ctx context.Context, deckID string, cardID string) (cards.Card, error)
+	PutCard(ctx context.Context, deckID string, card cards.Card) error
+	DeleteCard(ctx context.Context, deckID string, cardID string) error
+	ListCards(ctx context.Context, deckID string) ([]cards.Card, error)
 	IsEmpty() bool
 	IsValidAuthor(key string) bool
+	GetProgress(ctx context.Context, learnerID string, deckID string) (cards.Progress, error) // new progress if the learner has none yet
+	PutProgress(ctx context.Context, progress cards.Progress) error
+	GetSession(ctx context.Context, id string) (cards.Session, error)
+	PutSession(ctx context.Context, session cards.Session) error
+	GetTrash(ctx context.Context, deckID string) ([]cards.TrashItem, error)
+	GetTrashItem(ctx context.Context, id string) (cards.TrashItem, error)
+	PutTrashItem(ctx context.Context, item cards.TrashItem) error
+	DeleteTrashItem(ctx context.Context, id string) error
+	GetChanges(ctx context.Context, deckID string, cardID string) ([]cards.Change, error) // newest first, the whole deck's when cardID is empty
+	PutChange(ctx context.Context, change cards.Change) error
+	GetSlug(ctx context.Context, slug string) (string, error) // the ID of the deck that claimed the slug
+	PutSlug(ctx context.Context, slug string, deckID string) error
+	GetUser(ctx context.Context, name string) (cards.User, error)
+	PutUser(ctx context.Context, user cards.User) error
+	GetLogin(ctx context.Context, id string) (cards.Login, error)
+	PutLogin(ctx context.Context, login cards.Login) error
+	DeleteLogin(ctx context.Context, id string) error
 }
 
+// AuthorKeyStore is a data store that can add and list author keys itself, rather than them being
+// managed elsewhere. Keys are trimmed of surrounding spaces, both when added and when checked.
+type AuthorKeyStore interface {
+	GetAuthorKeys(ctx context.Context) (map[string]string, error) // the role of each key
+	PutAuthorKey(ctx context.Context, key string, role string) error
+}
+
+// SchemaUpgrader is a data store that can upgrade every stored deck and card to the current schema
+// version in one go, rather than each one being upgraded as it is read. It returns the number of
+// documents that were rewritten.
+type SchemaUpgrader interface {
+	UpgradeSchema(ctx context.Context) (int, error)
+}
+
+// The author key that TestDataStore always accepts
+const TEST_AUTHOR_KEY = "guessme"
+
+// TestDataStore keeps everything in memory, guarded so that it can be shared by concurrent requests.
 type TestDataStore struct {
-	decks map[string]cards.Deck
+	mu       sync.RWMutex
+	decks    map[string]cards.Deck // without their cards
+	cards    map[string]map[string]cards.Card
+	progress map[string]cards.Progress
+	sessions map[string]cards.Session
+	trash    map[string]cards.TrashItem
+	changes  map[string]cards.Change
+	slugs    map[string]string
+	keys     map[string]string
+	users    map[string]cards.User
+	logins   map[string]cards.Login
 }
 
 func (store *TestDataStore) Summary() string {
@@ -24,28 +100,374 @@ func (store *TestDataStore) Summary() string {
 }
 
 func (store *TestDataStore) Init(ctx context.Context) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.init()
+}
+
+// init empties the store, and must be called with the lock held
+func (store *TestDataStore) init() {
 	store.decks = make(map[string]cards.Deck)
+	store.cards = make(map[string]map[string]cards.Card)
+	store.progress = make(map[string]cards.Progress)
+	store.sessions = make(map[string]cards.Session)
+	store.trash = make(map[string]cards.TrashItem)
+	store.changes = make(map[string]cards.Change)
+	store.slugs = make(map[string]string)
+	store.keys = make(map[string]string)
+	store.users = make(map[string]cards.User)
+	store.logins = make(map[string]cards.Login)
 }
 
-func (store *TestDataStore) GetDeck(ctx context.Context, id string) cards.Deck {
+func (store *TestDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.getDeck(id)
+}
+
+// getDeck must be called with the lock held
+func (store *TestDataStore) getDeck(id string) (cards.Deck, error) {
 	deck, ok := store.decks[id]
 	if !ok {
-		deck = *new(cards.Deck)
+		return deck, fmt.Errorf("deck %s %w", id, ErrNotFound)
+	}
+	// A fresh map every time, so that callers changing it do not change the stored deck
+	deck.Cards = make(map[string]cards.Card)
+	for cardID, card := range store.cards[id] {
+		deck.Cards[cardID] = card
 	}
-	return deck
+	return deck, nil
 }
 
-func (store *TestDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) {
+func (store *TestDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
 	if store.decks == nil {
-		store.Init(ctx)
+		store.init()
+	}
+	current, ok := store.decks[id]
+	if ok && current.Version != deck.Version {
+		return fmt.Errorf("deck %s is at version %d not %d, %w", id, current.Version, deck.Version, ErrConflict)
+	}
+	if ok {
+		deck.Created, deck.CreatedBy = current.Created, current.CreatedBy
+	} else if deck.Created.IsZero() {
+		deck.Created = time.Now().UTC()
+	}
+	if store.cards[id] == nil {
+		store.cards[id] = make(map[string]cards.Card)
 	}
+	for cardID, card := range deck.Cards {
+		card.ID = cardID
+		card.DeckID = id
+		card.Version++
+		store.cards[id][cardID] = card
+	}
+	deck.Cards = nil
+	deck.Version++
 	store.decks[id] = deck
+	return nil
+}
+
+func (store *TestDataStore) ListDecks(ctx context.Context, query DeckQuery) (DeckPage, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	decks := make([]DeckSummary, 0, len(store.decks))
+	for id, deck := range store.decks {
+		summary := SummariseDeck(deck)
+		summary.CardCount = len(store.cards[id])
+		decks = append(decks, summary)
+	}
+	return PageDecks(decks, query)
+}
+
+func (store *TestDataStore) GetChildDecks(ctx context.Context, parentID string) ([]cards.Deck, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	children := make([]cards.Deck, 0)
+	for id, deck := range store.decks {
+		if deck.ParentID == parentID {
+			deck, _ = store.getDeck(id)
+			children = append(children, deck)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Title < children[j].Title
+	})
+	return children, nil
+}
+
+func (store *TestDataStore) DeleteDeck(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.decks[id]; !ok {
+		return fmt.Errorf("deck %s %w", id, ErrNotFound)
+	}
+	delete(store.decks, id)
+	delete(store.cards, id)
+	return nil
+}
+
+func (store *TestDataStore) GetCard(ctx context.Context, deckID string, cardID string) (cards.Card, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	card, ok := store.cards[deckID][cardID]
+	if !ok {
+		return card, fmt.Errorf("card %s in deck %s %w", cardID, deckID, ErrNotFound)
+	}
+	return card, nil
+}
+
+func (store *TestDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.decks[deckID]; !ok {
+		return fmt.Errorf("deck %s %w", deckID, ErrNotFound)
+	}
+	if current, ok := store.cards[deckID][card.ID]; ok && current.Version != card.Version {
+		return fmt.Errorf("card %s is at version %d not %d, %w", card.ID, current.Version, card.Version, ErrConflict)
+	}
+	card.DeckID = deckID
+	card.Version++
+	store.cards[deckID][card.ID] = card
+	return nil
+}
+
+func (store *TestDataStore) DeleteCard(ctx context.Context, deckID string, cardID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.cards[deckID][cardID]; !ok {
+		return fmt.Errorf("card %s in deck %s %w", cardID, deckID, ErrNotFound)
+	}
+	delete(store.cards[deckID], cardID)
+	return nil
+}
+
+func (store *TestDataStore) ListCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	list := make([]cards.Card, 0)
+	if _, ok := store.decks[deckID]; !ok {
+		return list, fmt.Errorf("deck %s %w", deckID, ErrNotFound)
+	}
+	for _, card := range store.cards[deckID] {
+		list = append(list, card)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
 }
 
 func (store *TestDataStore) IsEmpty() bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
 	return (store.decks == nil) || (len(store.decks) == 0)
 }
 
 func (store *TestDataStore) IsValidAuthor(key string) bool {
-	return key == "guessme"
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	key = strings.TrimSpace(key)
+	return key == TEST_AUTHOR_KEY || store.keys[key] == "author"
+}
+
+func (store *TestDataStore) GetAuthorKeys(ctx context.Context) (map[string]string, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	keys := make(map[string]string)
+	for key, role := range store.keys {
+		keys[key] = role
+	}
+	return keys, nil
+}
+
+func (store *TestDataStore) PutAuthorKey(ctx context.Context, key string, role string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.keys == nil {
+		store.init()
+	}
+	store.keys[strings.TrimSpace(key)] = role
+	return nil
+}
+
+func (store *TestDataStore) GetProgress(ctx context.Context, learnerID string, deckID string) (cards.Progress, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	progress, ok := store.progress[learnerID+"/"+deckID]
+	if !ok {
+		progress = cards.Progress{LearnerID: learnerID, DeckID: deckID}
+	}
+	return progress, nil
+}
+
+func (store *TestDataStore) PutProgress(ctx context.Context, progress cards.Progress) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.progress == nil {
+		store.init()
+	}
+	store.progress[progress.LearnerID+"/"+progress.DeckID] = progress
+	return nil
+}
+
+func (store *TestDataStore) GetSession(ctx context.Context, id string) (cards.Session, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	session, ok := store.sessions[id]
+	if !ok {
+		return session, fmt.Errorf("session %s %w", id, ErrNotFound)
+	}
+	return session, nil
+}
+
+func (store *TestDataStore) PutSession(ctx context.Context, session cards.Session) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.sessions == nil {
+		store.init()
+	}
+	store.sessions[session.ID] = session
+	return nil
+}
+
+func (store *TestDataStore) GetTrash(ctx context.Context, deckID string) ([]cards.TrashItem, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	items := make([]cards.TrashItem, 0)
+	for _, item := range store.trash {
+		if item.DeckID == deckID {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Deleted.After(items[j].Deleted)
+	})
+	return items, nil
+}
+
+func (store *TestDataStore) GetTrashItem(ctx context.Context, id string) (cards.TrashItem, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	item, ok := store.trash[id]
+	if !ok {
+		return item, fmt.Errorf("trash item %s %w", id, ErrNotFound)
+	}
+	return item, nil
+}
+
+func (store *TestDataStore) PutTrashItem(ctx context.Context, item cards.TrashItem) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.trash == nil {
+		store.init()
+	}
+	store.trash[item.ID] = item
+	return nil
+}
+
+func (store *TestDataStore) DeleteTrashItem(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.trash, id)
+	return nil
+}
+
+func (store *TestDataStore) GetChanges(ctx context.Context, deckID string, cardID string) ([]cards.Change, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	changes := make([]cards.Change, 0)
+	for _, change := range store.changes {
+		if change.DeckID == deckID && (cardID == "" || change.CardID == cardID) {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID > changes[j].ID
+	})
+	return changes, nil
+}
+
+func (store *TestDataStore) PutChange(ctx context.Context, change cards.Change) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.changes == nil {
+		store.init()
+	}
+	store.changes[change.ID] = change
+	return nil
+}
+
+func (store *TestDataStore) GetSlug(ctx context.Context, slug string) (string, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	deckID, ok := store.slugs[slug]
+	if !ok {
+		return "", fmt.Errorf("slug %s %w", slug, ErrNotFound)
+	}
+	return deckID, nil
+}
+
+func (store *TestDataStore) PutSlug(ctx context.Context, slug string, deckID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.slugs == nil {
+		store.init()
+	}
+	if current, ok := store.slugs[slug]; ok && current != deckID {
+		return fmt.Errorf("slug %s belongs to deck %s, %w", slug, current, ErrConflict)
+	}
+	store.slugs[slug] = deckID
+	return nil
+}
+
+func (store *TestDataStore) GetUser(ctx context.Context, name string) (cards.User, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	user, ok := store.users[name]
+	if !ok {
+		return user, fmt.Errorf("user %s %w", name, ErrNotFound)
+	}
+	return user, nil
+}
+
+func (store *TestDataStore) PutUser(ctx context.Context, user cards.User) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.users == nil {
+		store.init()
+	}
+	if _, ok := store.users[user.Name]; ok {
+		return fmt.Errorf("user %s already registered, %w", user.Name, ErrConflict)
+	}
+	store.users[user.Name] = user
+	return nil
+}
+
+func (store *TestDataStore) GetLogin(ctx context.Context, id string) (cards.Login, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	login, ok := store.logins[id]
+	if !ok {
+		return login, fmt.Errorf("login %w", ErrNotFound)
+	}
+	return login, nil
+}
+
+func (store *TestDataStore) PutLogin(ctx context.Context, login cards.Login) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.logins == nil {
+		store.init()
+	}
+	store.logins[login.ID] = login
+	return nil
+}
+
+func (store *TestDataStore) DeleteLogin(ctx context.Context, id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.logins, id)
+	return nil
 }
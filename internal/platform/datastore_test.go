@@ -0,0 +1,59 @@
+package platform_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+	"flashcards/internal/test"
+)
+
+func TestDataStoreConformance(t *testing.T) {
+	test.RunDataStoreConformance(t, func(t *testing.T) platform.DataStore {
+		store := &platform.TestDataStore{}
+		store.Init(context.Background())
+		return store
+	})
+}
+
+func TestCachingDataStoreConformance(t *testing.T) {
+	test.RunDataStoreConformance(t, func(t *testing.T) platform.DataStore {
+		store := &platform.TestDataStore{}
+		store.Init(context.Background())
+		return platform.NewCachingDataStore(store, &test.LogRecorder{}, time.Minute, 10)
+	})
+}
+
+func TestDataStoreConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	store := &platform.TestDataStore{}
+	store.Init(ctx)
+	deck := cards.Deck{ID: "D", Title: "Shared"}
+	store.PutDeck(ctx, "D", deck)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			card := cards.Card{ID: fmt.Sprintf("C%d", i), Question: "Q"}
+			if err := store.PutCard(ctx, "D", card); err != nil {
+				t.Errorf("PutCard failed: %v", err)
+			}
+			store.PutProgress(ctx, cards.Progress{LearnerID: fmt.Sprintf("L%d", i), DeckID: "D"})
+			store.GetDeck(ctx, "D")
+			store.GetChildDecks(ctx, "")
+			store.ListDecks(ctx, platform.DeckQuery{})
+		}(i)
+	}
+	wg.Wait()
+
+	list, err := store.ListCards(ctx, "D")
+	if err != nil || len(list) != 20 {
+		t.Errorf("Expected 20 cards, got %d, %v", len(list), err)
+	}
+}
@@ -0,0 +1,171 @@
+package platform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"flashcards/internal/cards"
+)
+
+// The orders ListDecks can return decks in
+const SORT_BY_TITLE = "title"
+const SORT_BY_CREATED = "created"
+const SORT_BY_CARDS = "cards"
+
+// The number of decks in a page when the query does not say, and the most it can ask for
+const DECK_PAGE_SIZE = 50
+const MAX_DECK_PAGE_SIZE = 500
+
+// DeckQuery picks out a page of decks for ListDecks. Decks with the same sort value are ordered
+// by ID, so that every deck has a fixed place in the listing.
+type DeckQuery struct {
+	Sort       string // SORT_BY_TITLE if empty
+	Descending bool
+	Title      string // only decks with this in their title, ignoring case
+	TopLevel   bool   // only decks that are not sub-decks
+	Cursor     string // the NextCursor of the previous page, empty for the first page
+	Limit      int
+}
+
+// DeckSummary is a deck as it is listed, without its cards
+type DeckSummary struct {
+	ID        string
+	Title     string
+	ParentID  string
+	Created   time.Time
+	CardCount int
+}
+
+type DeckPage struct {
+	Decks      []DeckSummary
+	NextCursor string // empty on the last page
+}
+
+func SummariseDeck(deck cards.Deck) DeckSummary {
+	return DeckSummary{
+		ID:        deck.ID,
+		Title:     deck.Title,
+		ParentID:  deck.ParentID,
+		Created:   deck.Created,
+		CardCount: len(deck.Cards),
+	}
+}
+
+// Check fills in the defaults of a query and rejects ones that cannot be answered
+func (query *DeckQuery) Check() error {
+	switch query.Sort {
+	case "":
+		query.Sort = SORT_BY_TITLE
+	case SORT_BY_TITLE, SORT_BY_CREATED, SORT_BY_CARDS:
+	default:
+		return fmt.Errorf("decks cannot be sorted by %q", query.Sort)
+	}
+	if query.Limit <= 0 {
+		query.Limit = DECK_PAGE_SIZE
+	} else if query.Limit > MAX_DECK_PAGE_SIZE {
+		query.Limit = MAX_DECK_PAGE_SIZE
+	}
+	// The cursor comes back from the browser, so it may have been cut short or changed
+	_, _, err := query.DecodeCursor()
+	return err
+}
+
+// Matches reports whether a deck passes the query's filters
+func (query DeckQuery) Matches(deck DeckSummary) bool {
+	if query.TopLevel && deck.ParentID != "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(deck.Title), strings.ToLower(query.Title))
+}
+
+// Before reports whether deck a comes before deck b in the query's order
+func (query DeckQuery) Before(a DeckSummary, b DeckSummary) bool {
+	if query.Descending {
+		a, b = b, a
+	}
+	var less, same bool
+	switch query.Sort {
+	case SORT_BY_CREATED:
+		less, same = a.Created.Before(b.Created), a.Created.Equal(b.Created)
+	case SORT_BY_CARDS:
+		less, same = a.CardCount < b.CardCount, a.CardCount == b.CardCount
+	default:
+		less, same = a.Title < b.Title, a.Title == b.Title
+	}
+	if same {
+		return a.ID < b.ID
+	}
+	return less
+}
+
+// cursor is the last deck of a page, with only the fields that decide where the next page starts
+type cursor struct {
+	ID    string
+	Title string    `json:",omitempty"`
+	Time  time.Time `json:",omitempty"`
+	Cards int       `json:",omitempty"`
+}
+
+// EncodeCursor makes the cursor for the page that follows a deck
+func (query DeckQuery) EncodeCursor(last DeckSummary) string {
+	position := cursor{ID: last.ID}
+	switch query.Sort {
+	case SORT_BY_CREATED:
+		position.Time = last.Created
+	case SORT_BY_CARDS:
+		position.Cards = last.CardCount
+	default:
+		position.Title = last.Title
+	}
+	data, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the deck that the page starts after, or false for the first page
+func (query DeckQuery) DecodeCursor() (DeckSummary, bool, error) {
+	if query.Cursor == "" {
+		return DeckSummary{}, false, nil
+	}
+	var position cursor
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &position)
+	}
+	if err != nil || position.ID == "" {
+		return DeckSummary{}, false, fmt.Errorf("invalid deck cursor %q", query.Cursor)
+	}
+	return DeckSummary{ID: position.ID, Title: position.Title, Created: position.Time, CardCount: position.Cards}, true, nil
+}
+
+// PageDecks filters, sorts and pages a list of every deck, for stores that cannot do that themselves
+func PageDecks(decks []DeckSummary, query DeckQuery) (DeckPage, error) {
+	page := DeckPage{Decks: make([]DeckSummary, 0)}
+	if err := query.Check(); err != nil {
+		return page, err
+	}
+	after, paged, err := query.DecodeCursor()
+	if err != nil {
+		return page, err
+	}
+
+	matching := make([]DeckSummary, 0, len(decks))
+	for _, deck := range decks {
+		if query.Matches(deck) && (!paged || query.Before(after, deck)) {
+			matching = append(matching, deck)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return query.Before(matching[i], matching[j])
+	})
+
+	if len(matching) > query.Limit {
+		matching = matching[:query.Limit]
+		page.NextCursor = query.EncodeCursor(matching[query.Limit-1])
+	}
+	page.Decks = matching
+	return page, nil
+}
@@ -0,0 +1,11 @@
+package platform
+
+import "errors"
+
+// Errors returned by DataStore operations, usually wrapped with details of what failed.
+// Use errors.Is to check for them.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflicting change")
+	ErrUnavailable = errors.New("data store unavailable")
+)
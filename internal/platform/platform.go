@@ -31,15 +31,19 @@ type TestPlatform struct {
 
 func LocalPlatform(ctx context.Context) Platform {
 	if platform == nil {
-		tp := &TestPlatform{}
-		tp.logs = *new(ConsoleLogger)
-		tp.store = *new(TestDataStore)
-		tp.store.Init(ctx)
-		platform = tp
+		platform = NewLocalPlatform(ctx)
 	}
 	return platform
 }
 
+// NewLocalPlatform returns a platform with its own empty in-memory store, unlike LocalPlatform which
+// always returns the same one.
+func NewLocalPlatform(ctx context.Context) Platform {
+	tp := &TestPlatform{}
+	tp.store.Init(ctx)
+	return tp
+}
+
 func (platform *TestPlatform) Logger() Logger {
 	return &platform.logs
 }
@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"flashcards/internal/cards"
+)
+
+// addCardIDs fills in the ID and DeckID of cards written when cards were only known by their key
+// in the deck's map of cards.
+func addCardIDs(deckID string, cardID string, card Document) error {
+	if id, _ := card["ID"].(string); id == "" {
+		card["ID"] = cardID
+	}
+	if id, _ := card["DeckID"].(string); id == "" {
+		card["DeckID"] = deckID
+	}
+	return nil
+}
+
+// tidyTags lower-cases tags and removes blank and repeated ones, as cards.ParseTags does for tags
+// entered on the edit form, so that tags written before then match when filtering.
+func tidyTags(deckID string, cardID string, card Document) error {
+	value, ok := card["Tags"]
+	if !ok || value == nil {
+		return nil
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return fmt.Errorf("tags are a %T", value)
+	}
+	tags := make([]string, 0, len(list))
+	for _, tag := range list {
+		text, ok := tag.(string)
+		if !ok {
+			return fmt.Errorf("tag %v is a %T", tag, tag)
+		}
+		// Commas would split the tag when it is next edited, so they are treated as separators now
+		tags = append(tags, text)
+	}
+	tidied := make([]any, 0, len(tags))
+	for _, tag := range cards.ParseTags(strings.Join(tags, ",")) {
+		tidied = append(tidied, tag)
+	}
+	card["Tags"] = tidied
+	return nil
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Document is a stored deck or card as it was read, before being decoded into a cards.Deck or
+// cards.Card, so that migrations can see fields that no longer exist and fix ones that were
+// written differently.
+type Document map[string]any
+
+// The field that stored decks and cards keep their schema version in. Documents written before
+// it was added have no version, which is read as version 0.
+const VERSION_FIELD = "SchemaVersion"
+
+// A Migration upgrades documents from the previous schema version to Version. Either function
+// can be nil when the migration only changes decks or only changes cards. The card function is
+// given the IDs the card is stored under, which older documents may not have inside them.
+type Migration struct {
+	Version     int
+	Description string
+	Deck        func(deck Document) error
+	Card        func(deckID string, cardID string, card Document) error
+}
+
+// migrations are applied in order, and each one's version must be one more than the one before
+var migrations = []Migration{
+	{Version: 1, Description: "cards have their own IDs", Card: addCardIDs},
+	{Version: 2, Description: "card tags are tidied", Card: tidyTags},
+}
+
+// CurrentVersion is the schema version that documents are written at
+func CurrentVersion() int {
+	return len(migrations)
+}
+
+// Migrations lists every migration, oldest first
+func Migrations() []Migration {
+	return migrations
+}
+
+// Version reads the schema version of a document, which may have been decoded from JSON or
+// read from Firestore
+func Version(doc Document) (int, error) {
+	switch value := doc[VERSION_FIELD].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return value, nil
+	case int64:
+		return int(value), nil
+	case float64:
+		if value != math.Trunc(value) {
+			return 0, fmt.Errorf("schema version %v is not a whole number", value)
+		}
+		return int(value), nil
+	case json.Number:
+		version, err := value.Int64()
+		return int(version), err
+	default:
+		return 0, fmt.Errorf("schema version %v is a %T", value, value)
+	}
+}
+
+// UpgradeDeck applies every migration the deck has not had yet to it and to the cards embedded
+// in it, returning whether anything was applied. Decks written by a newer version of the
+// application are an error, as they may have fields this version would lose.
+func UpgradeDeck(deckID string, deck Document) (bool, error) {
+	return upgrade(deck, func(migration Migration) error {
+		if migration.Deck != nil {
+			if err := migration.Deck(deck); err != nil {
+				return err
+			}
+		}
+		if migration.Card == nil {
+			return nil
+		}
+		embedded, _ := deck["Cards"].(map[string]any)
+		for cardID, value := range embedded {
+			card, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("card %s is a %T", cardID, value)
+			}
+			if err := migration.Card(deckID, cardID, card); err != nil {
+				return fmt.Errorf("card %s: %w", cardID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpgradeCard applies every migration the card has not had yet, for stores that keep cards in
+// documents of their own.
+func UpgradeCard(deckID string, cardID string, card Document) (bool, error) {
+	return upgrade(card, func(migration Migration) error {
+		if migration.Card == nil {
+			return nil
+		}
+		return migration.Card(deckID, cardID, card)
+	})
+}
+
+func upgrade(doc Document, apply func(migration Migration) error) (bool, error) {
+	version, err := Version(doc)
+	if err != nil {
+		return false, err
+	}
+	if version > CurrentVersion() {
+		return false, fmt.Errorf("schema version %d is newer than %d, which is the latest this version can read", version, CurrentVersion())
+	}
+	for _, migration := range migrations[version:] {
+		if err := apply(migration); err != nil {
+			return false, fmt.Errorf("migrating to schema version %d, %s: %w", migration.Version, migration.Description, err)
+		}
+		doc[VERSION_FIELD] = migration.Version
+	}
+	return version < CurrentVersion(), nil
+}
+
+// Decode reads a JSON document so that it can be upgraded
+func Decode(data []byte) (Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("document is null")
+	}
+	return doc, nil
+}
+
+// To decodes an upgraded document into a deck, card or other struct
+func (doc Document) To(into any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
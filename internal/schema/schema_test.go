@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"flashcards/internal/cards"
+)
+
+// Each fixture in testdata is a stored document, and the .want.json file next to it is what it
+// should be after upgrading. Deck fixtures are upgraded as deck ABCD-1234 and card fixtures as
+// card C3 in that deck.
+const FIXTURE_DECK_ID = "ABCD-1234"
+const FIXTURE_CARD_ID = "C3"
+
+func readFixture(t *testing.T, path string) Document {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading %s: %v", path, err)
+	}
+	doc, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Error decoding %s: %v", path, err)
+	}
+	return doc
+}
+
+func TestFixtures(t *testing.T) {
+	paths, _ := filepath.Glob(filepath.Join("testdata", "*.want.json"))
+	if len(paths) == 0 {
+		t.Fatal("No fixtures found")
+	}
+	for _, wantPath := range paths {
+		wantPath := wantPath
+		path := strings.TrimSuffix(wantPath, ".want.json") + ".json"
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			doc := readFixture(t, path)
+			before, _ := Version(doc)
+
+			var upgraded bool
+			var err error
+			if strings.HasPrefix(filepath.Base(path), "card-") {
+				upgraded, err = UpgradeCard(FIXTURE_DECK_ID, FIXTURE_CARD_ID, doc)
+			} else {
+				upgraded, err = UpgradeDeck(FIXTURE_DECK_ID, doc)
+			}
+			if err != nil {
+				t.Fatalf("Upgrade failed: %v", err)
+			}
+			if upgraded != (before < CurrentVersion()) {
+				t.Errorf("Upgrade from version %d reported %v", before, upgraded)
+			}
+
+			// Compare as decoded JSON, so that numbers have the same type on both sides
+			data, _ := json.Marshal(doc)
+			got, _ := Decode(data)
+			if want := readFixture(t, wantPath); !reflect.DeepEqual(got, want) {
+				t.Errorf("Upgraded document is\n%s", data)
+			}
+		})
+	}
+}
+
+func TestMigrationVersions(t *testing.T) {
+	for i, migration := range Migrations() {
+		if migration.Version != i+1 {
+			t.Errorf("Migration %d (%s) has version %d", i, migration.Description, migration.Version)
+		}
+		if migration.Deck == nil && migration.Card == nil {
+			t.Errorf("Migration %d (%s) does nothing", i, migration.Description)
+		}
+	}
+}
+
+func TestUpgradedDeckDecodes(t *testing.T) {
+	doc := readFixture(t, filepath.Join("testdata", "deck-v0.json"))
+	if _, err := UpgradeDeck(FIXTURE_DECK_ID, doc); err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	var deck cards.Deck
+	if err := doc.To(&deck); err != nil {
+		t.Fatalf("Error decoding deck: %v", err)
+	}
+	if card := deck.Cards["C1"]; card.ID != "C1" || card.DeckID != FIXTURE_DECK_ID || !card.HasTag("basic words") {
+		t.Errorf("Unexpected card %+v", card)
+	}
+}
+
+func TestNewerVersionRejected(t *testing.T) {
+	doc := Document{"ID": "D1", VERSION_FIELD: int64(CurrentVersion() + 1)}
+	if _, err := UpgradeDeck("D1", doc); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected error for newer schema version, got %v", err)
+	}
+}
+
+func TestBadCardRejected(t *testing.T) {
+	doc := Document{"ID": "D1", "Cards": map[string]any{"C1": map[string]any{"Tags": "not a list"}}}
+	if _, err := UpgradeDeck("D1", doc); err == nil || !strings.Contains(err.Error(), "card C1") {
+		t.Errorf("Expected error for bad tags, got %v", err)
+	}
+}
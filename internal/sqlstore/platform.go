@@ -0,0 +1,35 @@
+package sqlstore
+
+import (
+	"context"
+	"os"
+
+	"flashcards/internal/platform"
+)
+
+// LocalSqlitePlatform runs the application on a single machine, keeping its data in a SQLite database file.
+type LocalSqlitePlatform struct {
+	logs  platform.ConsoleLogger
+	store SqliteDataStore
+}
+
+func SqlitePlatform(ctx context.Context, path string) *LocalSqlitePlatform {
+	p := LocalSqlitePlatform{}
+	p.store = *NewSqliteDataStore(&p.logs, path)
+	return &p
+}
+
+func (platform *LocalSqlitePlatform) Logger() platform.Logger {
+	return &platform.logs
+}
+
+func (platform *LocalSqlitePlatform) DataStore() platform.DataStore {
+	return &platform.store
+}
+
+func (platform *LocalSqlitePlatform) ListenAddress() string {
+	if port := os.Getenv("PORT"); port != "" {
+		return ":" + port
+	}
+	return "127.0.0.1:8080"
+}
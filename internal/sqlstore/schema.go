@@ -0,0 +1,77 @@
+package sqlstore
+
+// The statements that create the SQLite schema, run in order by Init. Each one must be safe to run
+// against a database that already has the schema.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS decks (
+		id         TEXT PRIMARY KEY,
+		title      TEXT NOT NULL,
+		reversible INTEGER NOT NULL DEFAULT 0,
+		parent_id  TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS decks_parent ON decks (parent_id, title)`,
+	`CREATE TABLE IF NOT EXISTS cards (
+		deck_id      TEXT NOT NULL REFERENCES decks (id) ON DELETE CASCADE,
+		id           TEXT NOT NULL,
+		type         TEXT NOT NULL DEFAULT '',
+		question     TEXT NOT NULL DEFAULT '',
+		answer       TEXT NOT NULL DEFAULT '',
+		hint         TEXT NOT NULL DEFAULT '',
+		alternatives TEXT NOT NULL DEFAULT '[]',
+		choices      TEXT NOT NULL DEFAULT '[]',
+		reversible   INTEGER,
+		tags         TEXT NOT NULL DEFAULT '[]',
+		PRIMARY KEY (deck_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS author_keys (
+		key  TEXT PRIMARY KEY,
+		role TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS progress (
+		learner_id TEXT NOT NULL,
+		deck_id    TEXT NOT NULL,
+		reviews    TEXT NOT NULL,
+		PRIMARY KEY (learner_id, deck_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS sessions (
+		id   TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS trash (
+		id      TEXT PRIMARY KEY,
+		deck_id TEXT NOT NULL,
+		deleted INTEGER NOT NULL,
+		data    TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS trash_deck ON trash (deck_id, deleted)`,
+	`CREATE TABLE IF NOT EXISTS changes (
+		id      TEXT PRIMARY KEY,
+		deck_id TEXT NOT NULL,
+		card_id TEXT NOT NULL DEFAULT '',
+		data    TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS changes_deck ON changes (deck_id, card_id, id)`,
+	`CREATE TABLE IF NOT EXISTS slugs (
+		slug    TEXT PRIMARY KEY,
+		deck_id TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		name TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS logins (
+		id      TEXT PRIMARY KEY,
+		expires INTEGER NOT NULL,
+		data    TEXT NOT NULL
+	)`,
+}
+
+// Changes to the schema made after it was first released, applied in order to databases created
+// before them. The database's user_version records how many have been applied.
+var upgrades = []string{
+	`ALTER TABLE decks ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE cards ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE decks ADD COLUMN created INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE decks ADD COLUMN slug TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE decks ADD COLUMN created_by TEXT NOT NULL DEFAULT ''`,
+}
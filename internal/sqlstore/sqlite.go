@@ -0,0 +1,802 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// SqliteDataStore keeps decks, cards and author keys in their own tables of a SQLite database,
+// with progress, sessions and the trash stored as JSON documents.
+type SqliteDataStore struct {
+	DB   *sql.DB
+	Path string
+	Err  error
+	logs platform.Logger
+}
+
+func NewSqliteDataStore(logs platform.Logger, path string) *SqliteDataStore {
+	return &SqliteDataStore{Path: path, logs: logs}
+}
+
+func (store *SqliteDataStore) Summary() string {
+	return fmt.Sprintf("SqliteDataStore(%s)", store.Path)
+}
+
+// Init opens the database file, creating it and its schema if they do not exist yet.
+func (store *SqliteDataStore) Init(ctx context.Context) {
+	store.DB, store.Err = sql.Open("sqlite", "file:"+store.Path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if store.Err != nil {
+		store.logs.Error(ctx, "Failed to open SQLite database %s: %v", store.Path, store.Err)
+		return
+	}
+	// SQLite allows one writer at a time, so a single connection avoids lock errors between our own requests
+	store.DB.SetMaxOpenConns(1)
+
+	for _, statement := range schema {
+		if _, store.Err = store.DB.ExecContext(ctx, statement); store.Err != nil {
+			store.logs.Error(ctx, "Failed to create SQLite schema: %v", store.Err)
+			return
+		}
+	}
+	if store.Err = store.upgrade(ctx); store.Err != nil {
+		store.logs.Error(ctx, "Failed to upgrade SQLite schema: %v", store.Err)
+		return
+	}
+	store.logs.Info(ctx, "Initialised SQLite database %s", store.Path)
+}
+
+// upgrade applies the schema upgrades that the database does not have yet
+func (store *SqliteDataStore) upgrade(ctx context.Context) error {
+	var applied int
+	if err := store.DB.QueryRowContext(ctx, "PRAGMA user_version").Scan(&applied); err != nil {
+		return err
+	}
+	for n := applied; n < len(upgrades); n++ {
+		store.logs.Info(ctx, "Applying SQLite schema upgrade %d", n+1)
+		if _, err := store.DB.ExecContext(ctx, upgrades[n]); err != nil {
+			return err
+		}
+		// PRAGMA statements cannot take parameters
+		if _, err := store.DB.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", n+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *SqliteDataStore) Close() error {
+	return store.DB.Close()
+}
+
+// storeError converts a SQLite error into one of the DataStore errors, keeping the original details
+func (store *SqliteDataStore) storeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %v", platform.ErrNotFound, err)
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return fmt.Errorf("%w: %v", platform.ErrUnavailable, err)
+		case sqlite3.SQLITE_CONSTRAINT:
+			return fmt.Errorf("%w: %v", platform.ErrConflict, err)
+		}
+	}
+	return err
+}
+
+// ready checks that Init managed to open the database
+func (store *SqliteDataStore) ready() error {
+	if store.DB == nil || store.Err != nil {
+		return fmt.Errorf("%w: %v", platform.ErrUnavailable, store.Err)
+	}
+	return nil
+}
+
+func (store *SqliteDataStore) GetDeck(ctx context.Context, id string) (cards.Deck, error) {
+	store.logs.Debug(ctx, "Fetching SQLite deck %s", id)
+
+	deck := cards.Deck{}
+	if err := store.ready(); err != nil {
+		return deck, err
+	}
+
+	var created int64
+	row := store.DB.QueryRowContext(ctx, "SELECT id, title, reversible, parent_id, version, created, slug, created_by FROM decks WHERE id = ?", id)
+	if err := row.Scan(&deck.ID, &deck.Title, &deck.Reversible, &deck.ParentID, &deck.Version, &created, &deck.Slug, &deck.CreatedBy); err != nil {
+		return cards.Deck{}, store.storeError(err)
+	}
+	deck.Created = fromUnixNano(created)
+
+	list, err := store.listCards(ctx, id)
+	if err != nil {
+		return cards.Deck{}, err
+	}
+	deck.Cards = make(map[string]cards.Card)
+	for _, card := range list {
+		deck.Cards[card.ID] = card
+	}
+	return deck, nil
+}
+
+const CARD_COLUMNS = "id, deck_id, type, question, answer, hint, alternatives, choices, reversible, tags, version"
+
+// listCards reads the cards of a deck, without checking that the deck exists
+func (store *SqliteDataStore) listCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	list := make([]cards.Card, 0)
+
+	rows, err := store.DB.QueryContext(ctx, "SELECT "+CARD_COLUMNS+" FROM cards WHERE deck_id = ? ORDER BY id", deckID)
+	if err != nil {
+		return list, store.storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return list, store.storeError(err)
+		}
+		list = append(list, card)
+	}
+	return list, store.storeError(rows.Err())
+}
+
+// scanCard reads a card from a row of CARD_COLUMNS
+func scanCard(row interface{ Scan(dest ...any) error }) (cards.Card, error) {
+	var card cards.Card
+	var alternatives, choices, tags string
+	var reversible sql.NullBool
+	err := row.Scan(&card.ID, &card.DeckID, &card.Type, &card.Question, &card.Answer, &card.Hint,
+		&alternatives, &choices, &reversible, &tags, &card.Version)
+	if err != nil {
+		return card, err
+	}
+	if reversible.Valid {
+		card.Reversible = &reversible.Bool
+	}
+	if err := json.Unmarshal([]byte(alternatives), &card.Alternatives); err != nil {
+		return card, err
+	}
+	if err := json.Unmarshal([]byte(choices), &card.Choices); err != nil {
+		return card, err
+	}
+	return card, json.Unmarshal([]byte(tags), &card.Tags)
+}
+
+// PutDeck writes the deck and the cards in it in a single transaction, as long as the stored deck
+// is still at the version that was read.
+func (store *SqliteDataStore) PutDeck(ctx context.Context, id string, deck cards.Deck) error {
+	store.logs.Info(ctx, "Writing SQLite deck %s at version %d", id, deck.Version)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	tx, err := store.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.storeError(err)
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRowContext(ctx, "SELECT version FROM decks WHERE id = ?", id).Scan(&current)
+	if err == nil && current != deck.Version {
+		return fmt.Errorf("deck %s is at version %d not %d, %w", id, current, deck.Version, platform.ErrConflict)
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return store.storeError(err)
+	}
+
+	created := deck.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	// The created time and creator are only used for new decks, existing ones keep theirs
+	_, err = tx.ExecContext(ctx, `INSERT INTO decks (id, title, reversible, parent_id, version, created, slug, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET title = excluded.title, reversible = excluded.reversible,
+			parent_id = excluded.parent_id, version = excluded.version, slug = excluded.slug`,
+		id, deck.Title, deck.Reversible, deck.ParentID, deck.Version+1, created.UnixNano(), deck.Slug, deck.CreatedBy)
+	if err != nil {
+		return store.storeError(err)
+	}
+
+	for cardID, card := range deck.Cards {
+		card.ID = cardID
+		if err := writeCard(ctx, tx, id, card); err != nil {
+			return store.storeError(err)
+		}
+	}
+
+	return store.storeError(tx.Commit())
+}
+
+// writeCard inserts or replaces a card, with its version incremented
+func writeCard(ctx context.Context, tx *sql.Tx, deckID string, card cards.Card) error {
+	var reversible sql.NullBool
+	if card.Reversible != nil {
+		reversible = sql.NullBool{Bool: *card.Reversible, Valid: true}
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO cards (deck_id, id, type, question, answer, hint, alternatives, choices, reversible, tags, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (deck_id, id) DO UPDATE SET type = excluded.type, question = excluded.question, answer = excluded.answer,
+			hint = excluded.hint, alternatives = excluded.alternatives, choices = excluded.choices,
+			reversible = excluded.reversible, tags = excluded.tags, version = excluded.version`,
+		deckID, card.ID, card.Type, card.Question, card.Answer, card.Hint,
+		jsonList(card.Alternatives), jsonList(card.Choices), reversible, jsonList(card.Tags), card.Version+1)
+	return err
+}
+
+func (store *SqliteDataStore) GetCard(ctx context.Context, deckID string, cardID string) (cards.Card, error) {
+	store.logs.Debug(ctx, "Fetching SQLite card %s in deck %s", cardID, deckID)
+
+	if err := store.ready(); err != nil {
+		return cards.Card{}, err
+	}
+
+	row := store.DB.QueryRowContext(ctx, "SELECT "+CARD_COLUMNS+" FROM cards WHERE deck_id = ? AND id = ?", deckID, cardID)
+	card, err := scanCard(row)
+	if err != nil {
+		return cards.Card{}, store.storeError(err)
+	}
+	return card, nil
+}
+
+// PutCard writes a single card, as long as the stored card is still at the version that was read.
+func (store *SqliteDataStore) PutCard(ctx context.Context, deckID string, card cards.Card) error {
+	store.logs.Info(ctx, "Writing SQLite card %s in deck %s at version %d", card.ID, deckID, card.Version)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	tx, err := store.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.storeError(err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM decks WHERE id = ?", deckID).Scan(&exists)
+	if err != nil {
+		return store.storeError(err)
+	}
+
+	var current int
+	err = tx.QueryRowContext(ctx, "SELECT version FROM cards WHERE deck_id = ? AND id = ?", deckID, card.ID).Scan(&current)
+	if err == nil && current != card.Version {
+		return fmt.Errorf("card %s is at version %d not %d, %w", card.ID, current, card.Version, platform.ErrConflict)
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return store.storeError(err)
+	}
+
+	if err := writeCard(ctx, tx, deckID, card); err != nil {
+		return store.storeError(err)
+	}
+	return store.storeError(tx.Commit())
+}
+
+func (store *SqliteDataStore) DeleteCard(ctx context.Context, deckID string, cardID string) error {
+	store.logs.Info(ctx, "Deleting SQLite card %s in deck %s", cardID, deckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	result, err := store.DB.ExecContext(ctx, "DELETE FROM cards WHERE deck_id = ? AND id = ?", deckID, cardID)
+	if err != nil {
+		return store.storeError(err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return fmt.Errorf("card %s in deck %s %w", cardID, deckID, platform.ErrNotFound)
+	}
+	return nil
+}
+
+func (store *SqliteDataStore) ListCards(ctx context.Context, deckID string) ([]cards.Card, error) {
+	store.logs.Debug(ctx, "Listing SQLite cards in deck %s", deckID)
+
+	if err := store.ready(); err != nil {
+		return []cards.Card{}, err
+	}
+
+	var exists int
+	err := store.DB.QueryRowContext(ctx, "SELECT 1 FROM decks WHERE id = ?", deckID).Scan(&exists)
+	if err != nil {
+		return []cards.Card{}, store.storeError(err)
+	}
+	return store.listCards(ctx, deckID)
+}
+
+// jsonList encodes a list of strings for a JSON column, using an empty list rather than null
+func jsonList(list []string) string {
+	if list == nil {
+		list = []string{}
+	}
+	encoded, _ := json.Marshal(list)
+	return string(encoded)
+}
+
+func (store *SqliteDataStore) GetChildDecks(ctx context.Context, parentID string) ([]cards.Deck, error) {
+	store.logs.Debug(ctx, "Fetching SQLite child decks of %s", parentID)
+
+	children := make([]cards.Deck, 0)
+	if err := store.ready(); err != nil {
+		return children, err
+	}
+
+	rows, err := store.DB.QueryContext(ctx, "SELECT id FROM decks WHERE parent_id = ? ORDER BY title", parentID)
+	if err != nil {
+		return children, store.storeError(err)
+	}
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return children, store.storeError(err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return children, store.storeError(err)
+	}
+
+	for _, id := range ids {
+		deck, err := store.GetDeck(ctx, id)
+		if err != nil {
+			return children, err
+		}
+		children = append(children, deck)
+	}
+	return children, nil
+}
+
+// unixNano is how times are kept, as nanoseconds since the Unix epoch, with 0 for an unknown time
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano reads a time kept as nanoseconds since the Unix epoch, where 0 means unknown
+func fromUnixNano(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// The column each order of ListDecks sorts on
+var deckSortColumns = map[string]string{
+	platform.SORT_BY_TITLE:   "title",
+	platform.SORT_BY_CREATED: "created",
+	platform.SORT_BY_CARDS:   "card_count",
+}
+
+// ListDecks pages through the decks with a query that starts after the cursor's sort value and ID.
+// Titles are matched ignoring the case of ASCII letters only.
+func (store *SqliteDataStore) ListDecks(ctx context.Context, query platform.DeckQuery) (platform.DeckPage, error) {
+	page := platform.DeckPage{Decks: make([]platform.DeckSummary, 0)}
+	if err := store.ready(); err != nil {
+		return page, err
+	}
+	if err := query.Check(); err != nil {
+		return page, err
+	}
+	after, paged, err := query.DecodeCursor()
+	if err != nil {
+		return page, err
+	}
+
+	column := deckSortColumns[query.Sort]
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	statement := `SELECT id, title, parent_id, created, card_count FROM (
+			SELECT d.id, d.title, d.parent_id, d.created,
+				(SELECT COUNT(*) FROM cards c WHERE c.deck_id = d.id) AS card_count
+			FROM decks d
+		) WHERE instr(lower(title), lower(?)) > 0`
+	args := []any{query.Title}
+	if query.TopLevel {
+		statement += " AND parent_id = ''"
+	}
+	if paged {
+		var value any
+		switch query.Sort {
+		case platform.SORT_BY_CREATED:
+			value = unixNano(after.Created)
+		case platform.SORT_BY_CARDS:
+			value = after.CardCount
+		default:
+			value = after.Title
+		}
+		statement += fmt.Sprintf(" AND (%s %s ? OR (%s = ? AND id %s ?))", column, comparison, column, comparison)
+		args = append(args, value, value, after.ID)
+	}
+	statement += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, direction, direction)
+	args = append(args, query.Limit+1)
+
+	rows, err := store.DB.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return page, store.storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deck platform.DeckSummary
+		var created int64
+		if err := rows.Scan(&deck.ID, &deck.Title, &deck.ParentID, &created, &deck.CardCount); err != nil {
+			return page, store.storeError(err)
+		}
+		deck.Created = fromUnixNano(created)
+		page.Decks = append(page.Decks, deck)
+	}
+	if len(page.Decks) > query.Limit {
+		page.Decks = page.Decks[:query.Limit]
+		page.NextCursor = query.EncodeCursor(page.Decks[query.Limit-1])
+	}
+	return page, store.storeError(rows.Err())
+}
+
+func (store *SqliteDataStore) DeleteDeck(ctx context.Context, id string) error {
+	store.logs.Info(ctx, "Deleting SQLite deck %s", id)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+
+	result, err := store.DB.ExecContext(ctx, "DELETE FROM decks WHERE id = ?", id)
+	if err != nil {
+		return store.storeError(err)
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return fmt.Errorf("deck %s %w", id, platform.ErrNotFound)
+	}
+	return nil
+}
+
+func (store *SqliteDataStore) IsEmpty() bool {
+	if store.ready() != nil {
+		return false
+	}
+	var count int
+	store.DB.QueryRow("SELECT COUNT(*) FROM decks").Scan(&count)
+	return count == 0
+}
+
+func (store *SqliteDataStore) IsValidAuthor(key string) bool {
+	if store.ready() != nil {
+		return false
+	}
+	var role string
+	err := store.DB.QueryRow("SELECT role FROM author_keys WHERE key = ?", strings.TrimSpace(key)).Scan(&role)
+	if err != nil {
+		store.logs.Info(context.Background(), "Author key not found")
+		return false
+	}
+	if role != "author" {
+		store.logs.Info(context.Background(), "Key does not have author role")
+		return false
+	}
+	return true
+}
+
+func (store *SqliteDataStore) GetAuthorKeys(ctx context.Context) (map[string]string, error) {
+	keys := make(map[string]string)
+	if err := store.ready(); err != nil {
+		return keys, err
+	}
+
+	rows, err := store.DB.QueryContext(ctx, "SELECT key, role FROM author_keys")
+	if err != nil {
+		return keys, store.storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, role string
+		if err := rows.Scan(&key, &role); err != nil {
+			return keys, store.storeError(err)
+		}
+		keys[key] = role
+	}
+	return keys, store.storeError(rows.Err())
+}
+
+// PutAuthorKey adds or updates a key that lets people create decks.
+func (store *SqliteDataStore) PutAuthorKey(ctx context.Context, key string, role string) error {
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.DB.ExecContext(ctx, "INSERT INTO author_keys (key, role) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET role = excluded.role",
+		strings.TrimSpace(key), role)
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) GetProgress(ctx context.Context, learnerID string, deckID string) (cards.Progress, error) {
+	progress := cards.Progress{LearnerID: learnerID, DeckID: deckID}
+	if err := store.ready(); err != nil {
+		return progress, err
+	}
+
+	var reviews string
+	err := store.DB.QueryRowContext(ctx, "SELECT reviews FROM progress WHERE learner_id = ? AND deck_id = ?", learnerID, deckID).Scan(&reviews)
+	if errors.Is(err, sql.ErrNoRows) {
+		store.logs.Debug(ctx, "No progress found for learner %s on deck %s", learnerID, deckID)
+		return progress, nil
+	}
+	if err != nil {
+		return progress, store.storeError(err)
+	}
+	return progress, json.Unmarshal([]byte(reviews), &progress.Reviews)
+}
+
+func (store *SqliteDataStore) PutProgress(ctx context.Context, progress cards.Progress) error {
+	store.logs.Debug(ctx, "Writing progress for learner %s on deck %s", progress.LearnerID, progress.DeckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	reviews, err := json.Marshal(progress.Reviews)
+	if err != nil {
+		return err
+	}
+	_, err = store.DB.ExecContext(ctx, `INSERT INTO progress (learner_id, deck_id, reviews) VALUES (?, ?, ?)
+		ON CONFLICT (learner_id, deck_id) DO UPDATE SET reviews = excluded.reviews`,
+		progress.LearnerID, progress.DeckID, string(reviews))
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) GetSession(ctx context.Context, id string) (cards.Session, error) {
+	var session cards.Session
+	if err := store.ready(); err != nil {
+		return session, err
+	}
+
+	var data string
+	err := store.DB.QueryRowContext(ctx, "SELECT data FROM sessions WHERE id = ?", id).Scan(&data)
+	if err != nil {
+		return session, store.storeError(err)
+	}
+	return session, json.Unmarshal([]byte(data), &session)
+}
+
+func (store *SqliteDataStore) PutSession(ctx context.Context, session cards.Session) error {
+	store.logs.Debug(ctx, "Writing study session %s", session.ID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	_, err = store.DB.ExecContext(ctx, "INSERT INTO sessions (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data",
+		session.ID, string(data))
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) GetTrash(ctx context.Context, deckID string) ([]cards.TrashItem, error) {
+	items := make([]cards.TrashItem, 0)
+	if err := store.ready(); err != nil {
+		return items, err
+	}
+
+	rows, err := store.DB.QueryContext(ctx, "SELECT data FROM trash WHERE deck_id = ? ORDER BY deleted DESC", deckID)
+	if err != nil {
+		return items, store.storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		var item cards.TrashItem
+		if err := rows.Scan(&data); err != nil {
+			return items, store.storeError(err)
+		}
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, store.storeError(rows.Err())
+}
+
+func (store *SqliteDataStore) GetTrashItem(ctx context.Context, id string) (cards.TrashItem, error) {
+	var item cards.TrashItem
+	if err := store.ready(); err != nil {
+		return item, err
+	}
+
+	var data string
+	err := store.DB.QueryRowContext(ctx, "SELECT data FROM trash WHERE id = ?", id).Scan(&data)
+	if err != nil {
+		return item, store.storeError(err)
+	}
+	return item, json.Unmarshal([]byte(data), &item)
+}
+
+func (store *SqliteDataStore) PutTrashItem(ctx context.Context, item cards.TrashItem) error {
+	store.logs.Debug(ctx, "Writing trash item %s", item.ID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	_, err = store.DB.ExecContext(ctx, `INSERT INTO trash (id, deck_id, deleted, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET deck_id = excluded.deck_id, deleted = excluded.deleted, data = excluded.data`,
+		item.ID, item.DeckID, item.Deleted.UnixNano(), string(data))
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) DeleteTrashItem(ctx context.Context, id string) error {
+	store.logs.Debug(ctx, "Deleting trash item %s", id)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.DB.ExecContext(ctx, "DELETE FROM trash WHERE id = ?", id)
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) GetChanges(ctx context.Context, deckID string, cardID string) ([]cards.Change, error) {
+	changes := make([]cards.Change, 0)
+	if err := store.ready(); err != nil {
+		return changes, err
+	}
+
+	query := "SELECT data FROM changes WHERE deck_id = ? ORDER BY id DESC"
+	args := []any{deckID}
+	if cardID != "" {
+		query = "SELECT data FROM changes WHERE deck_id = ? AND card_id = ? ORDER BY id DESC"
+		args = append(args, cardID)
+	}
+	rows, err := store.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return changes, store.storeError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		var change cards.Change
+		if err := rows.Scan(&data); err != nil {
+			return changes, store.storeError(err)
+		}
+		if err := json.Unmarshal([]byte(data), &change); err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, store.storeError(rows.Err())
+}
+
+func (store *SqliteDataStore) PutChange(ctx context.Context, change cards.Change) error {
+	store.logs.Debug(ctx, "Writing change %s to deck %s", change.ID, change.DeckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	_, err = store.DB.ExecContext(ctx, `INSERT INTO changes (id, deck_id, card_id, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET deck_id = excluded.deck_id, card_id = excluded.card_id, data = excluded.data`,
+		change.ID, change.DeckID, change.CardID, string(data))
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) GetSlug(ctx context.Context, slug string) (string, error) {
+	if err := store.ready(); err != nil {
+		return "", err
+	}
+	var deckID string
+	err := store.DB.QueryRowContext(ctx, "SELECT deck_id FROM slugs WHERE slug = ?", slug).Scan(&deckID)
+	return deckID, store.storeError(err)
+}
+
+// PutSlug claims a slug for a deck, unless another deck has already claimed it.
+func (store *SqliteDataStore) PutSlug(ctx context.Context, slug string, deckID string) error {
+	store.logs.Info(ctx, "Claiming slug %s for deck %s", slug, deckID)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.DB.ExecContext(ctx, "INSERT INTO slugs (slug, deck_id) VALUES (?, ?) ON CONFLICT (slug) DO NOTHING", slug, deckID)
+	if err != nil {
+		return store.storeError(err)
+	}
+	current, err := store.GetSlug(ctx, slug)
+	if err == nil && current != deckID {
+		return fmt.Errorf("slug %s belongs to deck %s, %w", slug, current, platform.ErrConflict)
+	}
+	return err
+}
+
+func (store *SqliteDataStore) GetUser(ctx context.Context, name string) (cards.User, error) {
+	var user cards.User
+	if err := store.ready(); err != nil {
+		return user, err
+	}
+
+	var data string
+	err := store.DB.QueryRowContext(ctx, "SELECT data FROM users WHERE name = ?", name).Scan(&data)
+	if err != nil {
+		return user, store.storeError(err)
+	}
+	return user, json.Unmarshal([]byte(data), &user)
+}
+
+// PutUser adds a new user, the primary key refusing a name that is already registered.
+func (store *SqliteDataStore) PutUser(ctx context.Context, user cards.User) error {
+	store.logs.Info(ctx, "Adding user %s", user.Name)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	_, err = store.DB.ExecContext(ctx, "INSERT INTO users (name, data) VALUES (?, ?)", user.Name, string(data))
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) GetLogin(ctx context.Context, id string) (cards.Login, error) {
+	var login cards.Login
+	if err := store.ready(); err != nil {
+		return login, err
+	}
+
+	var data string
+	err := store.DB.QueryRowContext(ctx, "SELECT data FROM logins WHERE id = ?", id).Scan(&data)
+	if err != nil {
+		return login, store.storeError(err)
+	}
+	return login, json.Unmarshal([]byte(data), &login)
+}
+
+func (store *SqliteDataStore) PutLogin(ctx context.Context, login cards.Login) error {
+	store.logs.Debug(ctx, "Writing login for user %s", login.UserName)
+
+	if err := store.ready(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	_, err = store.DB.ExecContext(ctx, `INSERT INTO logins (id, expires, data) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET expires = excluded.expires, data = excluded.data`,
+		login.ID, login.Expires.UnixNano(), string(data))
+	return store.storeError(err)
+}
+
+func (store *SqliteDataStore) DeleteLogin(ctx context.Context, id string) error {
+	if err := store.ready(); err != nil {
+		return err
+	}
+	_, err := store.DB.ExecContext(ctx, "DELETE FROM logins WHERE id = ?", id)
+	return store.storeError(err)
+}
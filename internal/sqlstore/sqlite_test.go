@@ -0,0 +1,246 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+	"flashcards/internal/test"
+)
+
+func testStore(t *testing.T) *SqliteDataStore {
+	store := NewSqliteDataStore(&test.LogRecorder{}, filepath.Join(t.TempDir(), "flashcards.db"))
+	store.Init(context.Background())
+	if store.Err != nil {
+		t.Fatalf("Failed to initialise store: %v", store.Err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestDeckRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	if !store.IsEmpty() {
+		t.Error("New store should be empty")
+	}
+
+	reversible := true
+	deck := cards.Deck{ID: "D1", Title: "Deck", Reversible: true}
+	deck.AddCard(cards.Card{ID: "C1", Question: "Q1", Answer: "A1", Alternatives: []string{"a"}, Tags: []string{"t1", "t2"}})
+	deck.AddCard(cards.Card{ID: "C2", Type: cards.CHOICE_CARD, Question: "Q2", Choices: []string{"x", "y"}, Reversible: &reversible})
+
+	if err := store.PutDeck(ctx, deck.ID, deck); err != nil {
+		t.Fatalf("Failed to write deck: %v", err)
+	}
+
+	read, err := store.GetDeck(ctx, "D1")
+	if err != nil {
+		t.Fatalf("Failed to read deck: %v", err)
+	}
+	if read.Title != "Deck" || !read.Reversible || len(read.Cards) != 2 {
+		t.Errorf("Unexpected deck %+v", read)
+	}
+	if card := read.Cards["C1"]; card.DeckID != "D1" || len(card.Tags) != 2 || card.Alternatives[0] != "a" || card.Reversible != nil {
+		t.Errorf("Unexpected card %+v", card)
+	}
+	if card := read.Cards["C2"]; card.Choices[1] != "y" || card.Reversible == nil || !*card.Reversible {
+		t.Errorf("Unexpected card %+v", card)
+	}
+
+	store.DeleteCard(ctx, "D1", "C1")
+	read, _ = store.GetDeck(ctx, "D1")
+	if len(read.Cards) != 1 {
+		t.Errorf("Removed card still in deck: %+v", read.Cards)
+	}
+}
+
+func TestDeckVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	store.PutDeck(ctx, "D1", cards.Deck{ID: "D1", Title: "First"})
+	first, _ := store.GetDeck(ctx, "D1")
+	second, _ := store.GetDeck(ctx, "D1")
+	if first.Version != 1 {
+		t.Errorf("Expected version 1, got %d", first.Version)
+	}
+
+	first.Title = "Changed"
+	if err := store.PutDeck(ctx, "D1", first); err != nil {
+		t.Fatalf("Failed to write deck: %v", err)
+	}
+	second.Title = "Also changed"
+	if err := store.PutDeck(ctx, "D1", second); !errors.Is(err, platform.ErrConflict) {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+
+	read, _ := store.GetDeck(ctx, "D1")
+	if read.Title != "Changed" || read.Version != 2 {
+		t.Errorf("Unexpected deck %+v", read)
+	}
+}
+
+func TestCards(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	if err := store.PutCard(ctx, "MISSING", cards.Card{ID: "C1"}); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error for a missing deck, got %v", err)
+	}
+
+	deck := cards.Deck{ID: "D1", Title: "Deck"}
+	deck.AddCard(cards.Card{ID: "C1", Question: "Q1"})
+	store.PutDeck(ctx, "D1", deck)
+
+	if err := store.PutCard(ctx, "D1", cards.Card{ID: "C2", Question: "Q2"}); err != nil {
+		t.Fatalf("Failed to add card: %v", err)
+	}
+	card, err := store.GetCard(ctx, "D1", "C2")
+	if err != nil || card.Question != "Q2" || card.DeckID != "D1" || card.Version != 1 {
+		t.Errorf("Unexpected card %+v, %v", card, err)
+	}
+
+	stale := card
+	card.Question = "Changed"
+	if err := store.PutCard(ctx, "D1", card); err != nil {
+		t.Errorf("Failed to change card: %v", err)
+	}
+	if err := store.PutCard(ctx, "D1", stale); !errors.Is(err, platform.ErrConflict) {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+
+	// Writing the deck's settings leaves cards it does not include alone
+	read, _ := store.GetDeck(ctx, "D1")
+	read.Title = "Renamed"
+	read.Cards = nil
+	store.PutDeck(ctx, "D1", read)
+
+	list, err := store.ListCards(ctx, "D1")
+	if err != nil || len(list) != 2 || list[0].ID != "C1" || list[1].Question != "Changed" {
+		t.Errorf("Unexpected cards %+v, %v", list, err)
+	}
+
+	if err := store.DeleteCard(ctx, "D1", "C1"); err != nil {
+		t.Errorf("Failed to delete card: %v", err)
+	}
+	if _, err := store.GetCard(ctx, "D1", "C1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if err := store.DeleteCard(ctx, "D1", "C1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if _, err := store.ListCards(ctx, "MISSING"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestDeckNotFound(t *testing.T) {
+	store := testStore(t)
+
+	_, err := store.GetDeck(context.Background(), "MISSING")
+	if !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if err := store.DeleteDeck(context.Background(), "MISSING"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestChildDecksAndDelete(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	store.PutDeck(ctx, "P", cards.Deck{ID: "P", Title: "Parent"})
+	store.PutDeck(ctx, "B", cards.Deck{ID: "B", Title: "Beta", ParentID: "P"})
+	store.PutDeck(ctx, "A", cards.Deck{ID: "A", Title: "Alpha", ParentID: "P"})
+
+	children, err := store.GetChildDecks(ctx, "P")
+	if err != nil || len(children) != 2 || children[0].Title != "Alpha" {
+		t.Errorf("Unexpected child decks %v, %v", children, err)
+	}
+
+	if err := store.DeleteDeck(ctx, "A"); err != nil {
+		t.Errorf("Failed to delete deck: %v", err)
+	}
+	children, _ = store.GetChildDecks(ctx, "P")
+	if len(children) != 1 {
+		t.Errorf("Deleted deck still listed: %v", children)
+	}
+}
+
+func TestAuthorKeys(t *testing.T) {
+	store := testStore(t)
+
+	if store.IsValidAuthor("secret") {
+		t.Error("Unknown key accepted")
+	}
+	store.PutAuthorKey(context.Background(), "secret", "author")
+	if !store.IsValidAuthor(" secret ") {
+		t.Error("Author key not accepted")
+	}
+	store.PutAuthorKey(context.Background(), "secret", "reader")
+	if store.IsValidAuthor("secret") {
+		t.Error("Key without author role accepted")
+	}
+}
+
+func TestProgressAndSessions(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	progress, err := store.GetProgress(ctx, "L", "D")
+	if err != nil || progress.LearnerID != "L" || len(progress.Reviews) != 0 {
+		t.Errorf("Unexpected new progress %+v, %v", progress, err)
+	}
+	progress.Grade("C1", cards.Good, time.Now())
+	store.PutProgress(ctx, progress)
+	progress, _ = store.GetProgress(ctx, "L", "D")
+	if progress.GetReview("C1").Repetitions != 1 {
+		t.Errorf("Progress not saved: %+v", progress)
+	}
+
+	if _, err := store.GetSession(ctx, "S"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	session := cards.NewSessionForKeys("D", []string{"C1", "C2"})
+	store.PutSession(ctx, session)
+	read, err := store.GetSession(ctx, session.ID)
+	if err != nil || len(read.Order) != 2 {
+		t.Errorf("Unexpected session %+v, %v", read, err)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	store := testStore(t)
+
+	now := time.Now()
+	store.PutTrashItem(ctx, cards.TrashCard(cards.Card{ID: "C1", DeckID: "D", Question: "Old"}, now.Add(-time.Hour)))
+	store.PutTrashItem(ctx, cards.TrashCard(cards.Card{ID: "C2", DeckID: "D", Question: "New"}, now))
+
+	items, err := store.GetTrash(ctx, "D")
+	if err != nil || len(items) != 2 || items[0].Title() != "New" {
+		t.Errorf("Unexpected trash %+v, %v", items, err)
+	}
+
+	store.DeleteTrashItem(ctx, "C1")
+	if _, err := store.GetTrashItem(ctx, "C1"); !errors.Is(err, platform.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	item, err := store.GetTrashItem(ctx, "C2")
+	if err != nil || item.Card == nil || item.Card.Question != "New" {
+		t.Errorf("Unexpected trash item %+v, %v", item, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	test.RunDataStoreConformance(t, func(t *testing.T) platform.DataStore {
+		return testStore(t)
+	})
+}
@@ -0,0 +1,573 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"flashcards/internal/cards"
+	"flashcards/internal/platform"
+)
+
+// DataStoreFactory makes a new, empty and initialised store for each conformance test
+type DataStoreFactory func(t *testing.T) platform.DataStore
+
+// RunDataStoreConformance checks that a DataStore implementation behaves the way the handlers
+// expect every store to, with each check run as a sub-test against a store of its own.
+func RunDataStoreConformance(t *testing.T, newStore DataStoreFactory) {
+	checks := []struct {
+		name  string
+		check func(t *testing.T, ctx context.Context, store platform.DataStore)
+	}{
+		{"Empty", conformEmpty},
+		{"DeckRoundTrip", conformDeckRoundTrip},
+		{"DeckNotFound", conformDeckNotFound},
+		{"DeckVersions", conformDeckVersions},
+		{"ConcurrentCreate", conformConcurrentCreate},
+		{"ChildDecks", conformChildDecks},
+		{"DeleteDeck", conformDeleteDeck},
+		{"Cards", conformCards},
+		{"CardVersions", conformCardVersions},
+		{"AuthorKeys", conformAuthorKeys},
+		{"Progress", conformProgress},
+		{"Sessions", conformSessions},
+		{"Trash", conformTrash},
+		{"Changes", conformChanges},
+		{"ListDecks", conformListDecks},
+		{"Created", conformCreated},
+		{"Slugs", conformSlugs},
+		{"Users", conformUsers},
+		{"Logins", conformLogins},
+	}
+	for _, c := range checks {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			c.check(t, context.Background(), newStore(t))
+		})
+	}
+}
+
+// conformanceDeck is a deck using every card field
+func conformanceDeck(id string) cards.Deck {
+	reversible := false
+	deck := cards.Deck{ID: id, Title: "Deck " + id, Reversible: true}
+	deck.AddCard(cards.Card{ID: "C1", Question: "Q1", Answer: "A1", Hint: "H1", Alternatives: []string{"a1"}, Tags: []string{"t1", "t2"}})
+	deck.AddCard(cards.Card{ID: "C2", Type: cards.CHOICE_CARD, Question: "Q2", Answer: "A2", Choices: []string{"x", "y"}, Reversible: &reversible})
+	return deck
+}
+
+func expectError(t *testing.T, err error, expected error, action string) {
+	t.Helper()
+	if !errors.Is(err, expected) {
+		t.Errorf("Expected %v from %s, got %v", expected, action, err)
+	}
+}
+
+func conformEmpty(t *testing.T, ctx context.Context, store platform.DataStore) {
+	if !store.IsEmpty() {
+		t.Error("New store should be empty")
+	}
+	store.PutDeck(ctx, "D1", conformanceDeck("D1"))
+	if store.IsEmpty() {
+		t.Error("Store with a deck should not be empty")
+	}
+}
+
+func conformDeckRoundTrip(t *testing.T, ctx context.Context, store platform.DataStore) {
+	if err := store.PutDeck(ctx, "D1", conformanceDeck("D1")); err != nil {
+		t.Fatalf("Failed to write deck: %v", err)
+	}
+
+	deck, err := store.GetDeck(ctx, "D1")
+	if err != nil {
+		t.Fatalf("Failed to read deck: %v", err)
+	}
+	if deck.ID != "D1" || deck.Title != "Deck D1" || !deck.Reversible || deck.ParentID != "" || len(deck.Cards) != 2 {
+		t.Errorf("Unexpected deck %+v", deck)
+	}
+
+	c1 := deck.Cards["C1"]
+	if c1.ID != "C1" || c1.DeckID != "D1" || c1.Question != "Q1" || c1.Answer != "A1" || c1.Hint != "H1" || c1.Reversible != nil {
+		t.Errorf("Unexpected card %+v", c1)
+	}
+	if len(c1.Alternatives) != 1 || c1.Alternatives[0] != "a1" || len(c1.Tags) != 2 || c1.Tags[1] != "t2" || len(c1.Choices) != 0 {
+		t.Errorf("Unexpected card lists %+v", c1)
+	}
+	c2 := deck.Cards["C2"]
+	if c2.Type != cards.CHOICE_CARD || len(c2.Choices) != 2 || c2.Choices[1] != "y" || c2.Reversible == nil || *c2.Reversible {
+		t.Errorf("Unexpected card %+v", c2)
+	}
+
+	// Changing the deck that was read does not change the store
+	delete(deck.Cards, "C1")
+	if deck, _ := store.GetDeck(ctx, "D1"); len(deck.Cards) != 2 {
+		t.Errorf("Changing a deck that was read changed the store: %+v", deck.Cards)
+	}
+}
+
+func conformDeckNotFound(t *testing.T, ctx context.Context, store platform.DataStore) {
+	_, err := store.GetDeck(ctx, "MISSING")
+	expectError(t, err, platform.ErrNotFound, "GetDeck")
+	expectError(t, store.DeleteDeck(ctx, "MISSING"), platform.ErrNotFound, "DeleteDeck")
+	_, err = store.ListCards(ctx, "MISSING")
+	expectError(t, err, platform.ErrNotFound, "ListCards")
+	_, err = store.GetCard(ctx, "MISSING", "C1")
+	expectError(t, err, platform.ErrNotFound, "GetCard")
+	expectError(t, store.PutCard(ctx, "MISSING", cards.Card{ID: "C1"}), platform.ErrNotFound, "PutCard")
+}
+
+func conformDeckVersions(t *testing.T, ctx context.Context, store platform.DataStore) {
+	store.PutDeck(ctx, "D1", conformanceDeck("D1"))
+	first, _ := store.GetDeck(ctx, "D1")
+	second, _ := store.GetDeck(ctx, "D1")
+	if first.Version != 1 {
+		t.Errorf("Expected new deck at version 1, got %d", first.Version)
+	}
+
+	first.Title = "First"
+	first.Cards = nil
+	if err := store.PutDeck(ctx, "D1", first); err != nil {
+		t.Fatalf("Failed to write deck: %v", err)
+	}
+	second.Title = "Second"
+	expectError(t, store.PutDeck(ctx, "D1", second), platform.ErrConflict, "PutDeck with a stale version")
+
+	deck, _ := store.GetDeck(ctx, "D1")
+	if deck.Title != "First" || deck.Version != 2 {
+		t.Errorf("Unexpected deck after conflict %+v", deck)
+	}
+	if len(deck.Cards) != 2 {
+		t.Errorf("Writing a deck without cards removed its cards: %+v", deck.Cards)
+	}
+}
+
+// Two new decks written under the same ID at the same time, such as two decks given the same
+// random code, must not both succeed or the first would be silently lost
+func conformConcurrentCreate(t *testing.T, ctx context.Context, store platform.DataStore) {
+	for round := 0; round < 5; round++ {
+		id := fmt.Sprintf("D%d", round)
+		errs := make(chan error, 2)
+		for _, title := range []string{"First", "Second"} {
+			go func(title string) {
+				errs <- store.PutDeck(ctx, id, cards.Deck{ID: id, Title: title})
+			}(title)
+		}
+
+		succeeded := 0
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err == nil {
+				succeeded++
+			} else if !errors.Is(err, platform.ErrConflict) {
+				t.Errorf("Expected a conflict creating %s, got %v", id, err)
+			}
+		}
+		if succeeded != 1 {
+			t.Errorf("Expected exactly one create of %s to succeed, %d did", id, succeeded)
+		}
+		if deck, err := store.GetDeck(ctx, id); err != nil || deck.Version != 1 {
+			t.Errorf("Unexpected deck after concurrent creates %+v, %v", deck, err)
+		}
+	}
+}
+
+func conformChildDecks(t *testing.T, ctx context.Context, store platform.DataStore) {
+	store.PutDeck(ctx, "P", cards.Deck{ID: "P", Title: "Parent"})
+	for _, child := range []cards.Deck{
+		{ID: "B", Title: "Beta", ParentID: "P"},
+		{ID: "A", Title: "Alpha", ParentID: "P"},
+		{ID: "O", Title: "Other"},
+	} {
+		child.AddCard(cards.Card{ID: "C" + child.ID, Question: child.Title})
+		store.PutDeck(ctx, child.ID, child)
+	}
+
+	children, err := store.GetChildDecks(ctx, "P")
+	if err != nil {
+		t.Fatalf("Failed to read child decks: %v", err)
+	}
+	if len(children) != 2 || children[0].ID != "A" || children[1].ID != "B" {
+		t.Fatalf("Expected child decks in title order, got %+v", children)
+	}
+	if card := children[0].Cards["CA"]; card.Question != "Alpha" {
+		t.Errorf("Child deck read without its cards: %+v", children[0])
+	}
+
+	if children, _ := store.GetChildDecks(ctx, "A"); len(children) != 0 {
+		t.Errorf("Expected no child decks, got %+v", children)
+	}
+}
+
+func conformDeleteDeck(t *testing.T, ctx context.Context, store platform.DataStore) {
+	store.PutDeck(ctx, "D1", conformanceDeck("D1"))
+	if err := store.DeleteDeck(ctx, "D1"); err != nil {
+		t.Fatalf("Failed to delete deck: %v", err)
+	}
+	_, err := store.GetDeck(ctx, "D1")
+	expectError(t, err, platform.ErrNotFound, "GetDeck after DeleteDeck")
+
+	// A new deck with the same ID does not get the old cards
+	store.PutDeck(ctx, "D1", cards.Deck{ID: "D1", Title: "New"})
+	if deck, _ := store.GetDeck(ctx, "D1"); len(deck.Cards) != 0 {
+		t.Errorf("Cards of deleted deck still stored: %+v", deck.Cards)
+	}
+}
+
+func conformCards(t *testing.T, ctx context.Context, store platform.DataStore) {
+	store.PutDeck(ctx, "D1", conformanceDeck("D1"))
+
+	if err := store.PutCard(ctx, "D1", cards.Card{ID: "C3", Question: "Q3", Tags: []string{"t3"}}); err != nil {
+		t.Fatalf("Failed to add card: %v", err)
+	}
+	card, err := store.GetCard(ctx, "D1", "C3")
+	if err != nil || card.ID != "C3" || card.DeckID != "D1" || card.Question != "Q3" || len(card.Tags) != 1 {
+		t.Errorf("Unexpected card %+v, %v", card, err)
+	}
+
+	list, err := store.ListCards(ctx, "D1")
+	if err != nil || len(list) != 3 || list[0].ID != "C1" || list[2].ID != "C3" {
+		t.Errorf("Expected cards in ID order, got %+v, %v", list, err)
+	}
+
+	if err := store.DeleteCard(ctx, "D1", "C1"); err != nil {
+		t.Errorf("Failed to delete card: %v", err)
+	}
+	_, err = store.GetCard(ctx, "D1", "C1")
+	expectError(t, err, platform.ErrNotFound, "GetCard after DeleteCard")
+	expectError(t, store.DeleteCard(ctx, "D1", "C1"), platform.ErrNotFound, "DeleteCard of a missing card")
+	if deck, _ := store.GetDeck(ctx, "D1"); len(deck.Cards) != 2 {
+		t.Errorf("Unexpected cards after delete %+v", deck.Cards)
+	}
+}
+
+func conformCardVersions(t *testing.T, ctx context.Context, store platform.DataStore) {
+	store.PutDeck(ctx, "D1", conformanceDeck("D1"))
+	deck, _ := store.GetDeck(ctx, "D1")
+
+	card, _ := store.GetCard(ctx, "D1", "C1")
+	stale := card
+	card.Question = "Changed"
+	if err := store.PutCard(ctx, "D1", card); err != nil {
+		t.Fatalf("Failed to write card: %v", err)
+	}
+	expectError(t, store.PutCard(ctx, "D1", stale), platform.ErrConflict, "PutCard with a stale version")
+
+	card, _ = store.GetCard(ctx, "D1", "C1")
+	if card.Question != "Changed" || card.Version != stale.Version+1 {
+		t.Errorf("Unexpected card after conflict %+v", card)
+	}
+	if after, _ := store.GetDeck(ctx, "D1"); after.Version != deck.Version {
+		t.Errorf("Writing a card changed the deck version from %d to %d", deck.Version, after.Version)
+	}
+}
+
+func conformAuthorKeys(t *testing.T, ctx context.Context, store platform.DataStore) {
+	keys, ok := store.(platform.AuthorKeyStore)
+	if !ok {
+		t.Skip("Store does not add author keys")
+	}
+
+	if store.IsValidAuthor("unknown") {
+		t.Error("Unknown key accepted")
+	}
+	if err := keys.PutAuthorKey(ctx, " writer ", "author"); err != nil {
+		t.Fatalf("Failed to add author key: %v", err)
+	}
+	keys.PutAuthorKey(ctx, "reader", "learner")
+
+	if !store.IsValidAuthor("writer") || !store.IsValidAuthor("  writer\n") {
+		t.Error("Author key not accepted, with and without surrounding spaces")
+	}
+	if store.IsValidAuthor("reader") {
+		t.Error("Key without the author role accepted")
+	}
+
+	all, err := keys.GetAuthorKeys(ctx)
+	if err != nil || len(all) != 2 || all["writer"] != "author" || all["reader"] != "learner" {
+		t.Errorf("Unexpected author keys %v, %v", all, err)
+	}
+}
+
+func conformProgress(t *testing.T, ctx context.Context, store platform.DataStore) {
+	progress, err := store.GetProgress(ctx, "L1", "D1")
+	if err != nil || progress.LearnerID != "L1" || progress.DeckID != "D1" || len(progress.Reviews) != 0 {
+		t.Errorf("Unexpected new progress %+v, %v", progress, err)
+	}
+
+	progress.Leitner("C1", true, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err := store.PutProgress(ctx, progress); err != nil {
+		t.Fatalf("Failed to write progress: %v", err)
+	}
+
+	progress, _ = store.GetProgress(ctx, "L1", "D1")
+	if len(progress.Reviews) != 1 || progress.Reviews["C1"].Box != 2 {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+	if other, _ := store.GetProgress(ctx, "L2", "D1"); len(other.Reviews) != 0 {
+		t.Errorf("Progress shared between learners %+v", other)
+	}
+}
+
+func conformSessions(t *testing.T, ctx context.Context, store platform.DataStore) {
+	_, err := store.GetSession(ctx, "MISSING")
+	expectError(t, err, platform.ErrNotFound, "GetSession")
+
+	session := cards.Session{ID: "S1", DeckID: "D1", Order: []string{"C1", "C2"}, Position: 1}
+	if err := store.PutSession(ctx, session); err != nil {
+		t.Fatalf("Failed to write session: %v", err)
+	}
+	session, err = store.GetSession(ctx, "S1")
+	if err != nil || session.DeckID != "D1" || len(session.Order) != 2 || session.Position != 1 {
+		t.Errorf("Unexpected session %+v, %v", session, err)
+	}
+}
+
+func conformTrash(t *testing.T, ctx context.Context, store platform.DataStore) {
+	deleted := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deck := conformanceDeck("D2")
+	deck.ParentID = "D1"
+	store.PutTrashItem(ctx, cards.TrashCard(cards.Card{ID: "C1", DeckID: "D1", Question: "Q1"}, deleted))
+	store.PutTrashItem(ctx, cards.TrashDeck(deck, deleted.Add(time.Hour)))
+	store.PutTrashItem(ctx, cards.TrashCard(cards.Card{ID: "C9", DeckID: "OTHER"}, deleted))
+
+	items, err := store.GetTrash(ctx, "D1")
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Unexpected trash %+v, %v", items, err)
+	}
+	if items[0].ID != "D2" || !items[0].IsDeck() || len(items[0].Deck.Cards) != 2 || items[1].Card.Question != "Q1" {
+		t.Errorf("Expected trash newest first, got %+v", items)
+	}
+	if !items[1].Deleted.Equal(deleted) {
+		t.Errorf("Unexpected deletion time %v", items[1].Deleted)
+	}
+
+	_, err = store.GetTrashItem(ctx, "MISSING")
+	expectError(t, err, platform.ErrNotFound, "GetTrashItem")
+
+	if err := store.DeleteTrashItem(ctx, "C1"); err != nil {
+		t.Errorf("Failed to delete trash item: %v", err)
+	}
+	if err := store.DeleteTrashItem(ctx, "C1"); err != nil {
+		t.Errorf("Deleting a trash item twice failed: %v", err)
+	}
+	_, err = store.GetTrashItem(ctx, "C1")
+	expectError(t, err, platform.ErrNotFound, "GetTrashItem after DeleteTrashItem")
+}
+
+func conformChanges(t *testing.T, ctx context.Context, store platform.DataStore) {
+	made := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deck := conformanceDeck("D1")
+	before := deck.Cards["C1"]
+	after := before
+	after.Answer = "Changed"
+	store.PutChange(ctx, cards.DeckChange(cards.CHANGE_CREATED, nil, &deck, "key 1", made))
+	store.PutChange(ctx, cards.CardChange(cards.CHANGE_EDITED, &before, &after, "learner 1", made.Add(time.Minute)))
+	store.PutChange(ctx, cards.CardChange(cards.CHANGE_DELETED, &after, nil, "learner 2", made.Add(time.Hour)))
+	store.PutChange(ctx, cards.CardChange(cards.CHANGE_CREATED, nil, &cards.Card{ID: "C1", DeckID: "OTHER"}, "", made))
+
+	changes, err := store.GetChanges(ctx, "D1", "")
+	if err != nil || len(changes) != 3 {
+		t.Fatalf("Unexpected deck history %+v, %v", changes, err)
+	}
+	if changes[0].Action != cards.CHANGE_DELETED || changes[2].Action != cards.CHANGE_CREATED || !changes[2].IsDeck() {
+		t.Errorf("Expected history newest first, got %+v", changes)
+	}
+	if !changes[2].Time.Equal(made) || changes[2].Actor != "key 1" || changes[2].DeckAfter.Title != "Deck D1" {
+		t.Errorf("Unexpected deck change %+v", changes[2])
+	}
+
+	changes, err = store.GetChanges(ctx, "D1", "C1")
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("Unexpected card history %+v, %v", changes, err)
+	}
+	edit := changes[1]
+	if edit.CardBefore.Answer != "A1" || edit.CardAfter.Answer != "Changed" || len(edit.CardAfter.Tags) != 2 || changes[0].CardAfter != nil {
+		t.Errorf("Unexpected card changes %+v", changes)
+	}
+
+	if changes, err := store.GetChanges(ctx, "MISSING", ""); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no history for a missing deck, got %+v, %v", changes, err)
+	}
+}
+
+func conformCreated(t *testing.T, ctx context.Context, store platform.DataStore) {
+	store.PutDeck(ctx, "D1", conformanceDeck("D1"))
+	deck, _ := store.GetDeck(ctx, "D1")
+	if deck.Created.IsZero() || time.Since(deck.Created) > time.Minute {
+		t.Errorf("New deck not given a created time: %v", deck.Created)
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	restored := conformanceDeck("D2")
+	restored.Created = created
+	store.PutDeck(ctx, "D2", restored)
+	deck, _ = store.GetDeck(ctx, "D2")
+	if !deck.Created.Equal(created) {
+		t.Errorf("New deck's created time not kept: %v", deck.Created)
+	}
+
+	deck.Created = created.Add(time.Hour)
+	store.PutDeck(ctx, "D2", deck)
+	deck, _ = store.GetDeck(ctx, "D2")
+	if !deck.Created.Equal(created) {
+		t.Errorf("Created time changed by an update: %v", deck.Created)
+	}
+}
+
+func conformListDecks(t *testing.T, ctx context.Context, store platform.DataStore) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, deck := range []cards.Deck{
+		{ID: "D1", Title: "Apple"},
+		{ID: "D2", Title: "banana", ParentID: "D1"},
+		{ID: "D3", Title: "Cherry"},
+		{ID: "D4", Title: "apple pie"},
+	} {
+		deck.Created = created.Add(time.Duration(i) * time.Hour)
+		for c := 0; c < []int{2, 0, 1, 3}[i]; c++ {
+			deck.AddCard(cards.Card{ID: fmt.Sprintf("C%d", c)})
+		}
+		if err := store.PutDeck(ctx, deck.ID, deck); err != nil {
+			t.Fatalf("Failed to write deck: %v", err)
+		}
+	}
+
+	listed := func(query platform.DeckQuery) (string, platform.DeckPage) {
+		t.Helper()
+		page, err := store.ListDecks(ctx, query)
+		if err != nil {
+			t.Fatalf("Failed to list decks with %+v: %v", query, err)
+		}
+		ids := make([]string, 0)
+		for _, deck := range page.Decks {
+			ids = append(ids, deck.ID)
+		}
+		return strings.Join(ids, ","), page
+	}
+
+	ids, page := listed(platform.DeckQuery{Limit: 2})
+	if ids != "D1,D3" || page.NextCursor == "" {
+		t.Errorf("Unexpected first page by title %s, %q", ids, page.NextCursor)
+	}
+	ids, page = listed(platform.DeckQuery{Limit: 2, Cursor: page.NextCursor})
+	if ids != "D4,D2" || page.NextCursor != "" {
+		t.Errorf("Unexpected last page by title %s, %q", ids, page.NextCursor)
+	}
+	if deck := page.Decks[1]; deck.Title != "banana" || deck.ParentID != "D1" || deck.CardCount != 0 || !deck.Created.Equal(created.Add(time.Hour)) {
+		t.Errorf("Unexpected deck summary %+v", deck)
+	}
+
+	ids, page = listed(platform.DeckQuery{Sort: platform.SORT_BY_CARDS, Descending: true, Limit: 3})
+	if ids != "D4,D1,D3" || page.Decks[0].CardCount != 3 {
+		t.Errorf("Unexpected first page by card count %s, %+v", ids, page.Decks)
+	}
+	if ids, _ = listed(platform.DeckQuery{Sort: platform.SORT_BY_CARDS, Descending: true, Limit: 3, Cursor: page.NextCursor}); ids != "D2" {
+		t.Errorf("Unexpected last page by card count %s", ids)
+	}
+
+	ids, page = listed(platform.DeckQuery{Sort: platform.SORT_BY_CREATED, Limit: 1})
+	for page.NextCursor != "" {
+		var more string
+		more, page = listed(platform.DeckQuery{Sort: platform.SORT_BY_CREATED, Limit: 1, Cursor: page.NextCursor})
+		ids += "," + more
+	}
+	if ids != "D1,D2,D3,D4" {
+		t.Errorf("Unexpected decks by created time %s", ids)
+	}
+
+	if ids, _ = listed(platform.DeckQuery{Title: "APPLE", Sort: platform.SORT_BY_CREATED, Descending: true}); ids != "D4,D1" {
+		t.Errorf("Unexpected decks matching title %s", ids)
+	}
+	if ids, _ = listed(platform.DeckQuery{TopLevel: true}); ids != "D1,D3,D4" {
+		t.Errorf("Unexpected top-level decks %s", ids)
+	}
+
+	if _, err := store.ListDecks(ctx, platform.DeckQuery{Sort: "colour"}); err == nil {
+		t.Error("Expected error for unknown sort order")
+	}
+	if _, err := store.ListDecks(ctx, platform.DeckQuery{Cursor: "not a cursor"}); err == nil {
+		t.Error("Expected error for bad cursor")
+	}
+}
+
+func conformSlugs(t *testing.T, ctx context.Context, store platform.DataStore) {
+	_, err := store.GetSlug(ctx, "french-verbs")
+	expectError(t, err, platform.ErrNotFound, "reading an unclaimed slug")
+
+	if err := store.PutSlug(ctx, "french-verbs", "D1"); err != nil {
+		t.Fatalf("Failed to claim slug: %v", err)
+	}
+	if err := store.PutSlug(ctx, "french-verbs", "D1"); err != nil {
+		t.Errorf("Failed to claim slug again for the same deck: %v", err)
+	}
+	expectError(t, store.PutSlug(ctx, "french-verbs", "D2"), platform.ErrConflict, "claiming another deck's slug")
+	if deckID, err := store.GetSlug(ctx, "french-verbs"); err != nil || deckID != "D1" {
+		t.Errorf("Expected slug to belong to D1, got %q, %v", deckID, err)
+	}
+
+	deck := conformanceDeck("D1")
+	deck.Slug = "french-verbs"
+	store.PutDeck(ctx, "D1", deck)
+	if read, _ := store.GetDeck(ctx, "D1"); read.Slug != "french-verbs" {
+		t.Errorf("Deck's slug not kept: %q", read.Slug)
+	}
+}
+
+func conformUsers(t *testing.T, ctx context.Context, store platform.DataStore) {
+	_, err := store.GetUser(ctx, "ann")
+	expectError(t, err, platform.ErrNotFound, "reading an unregistered user")
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	user := cards.User{Name: "ann", PasswordHash: "$2a$10$hash", InvitedBy: "invite 1234ABCD", Created: created}
+	if err := store.PutUser(ctx, user); err != nil {
+		t.Fatalf("Failed to add user: %v", err)
+	}
+	read, err := store.GetUser(ctx, "ann")
+	if err != nil || read.Name != "ann" || read.PasswordHash != user.PasswordHash || read.InvitedBy != user.InvitedBy || !read.Created.Equal(created) {
+		t.Errorf("User did not round trip: %+v, %v", read, err)
+	}
+
+	expectError(t, store.PutUser(ctx, cards.User{Name: "ann", PasswordHash: "other"}), platform.ErrConflict, "registering a taken name")
+	if read, _ := store.GetUser(ctx, "ann"); read.PasswordHash != user.PasswordHash {
+		t.Errorf("Registering a taken name replaced the user: %+v", read)
+	}
+
+	// The creator of a deck is kept from when it was created
+	deck := conformanceDeck("D1")
+	deck.CreatedBy = "ann"
+	store.PutDeck(ctx, "D1", deck)
+	deck, _ = store.GetDeck(ctx, "D1")
+	if deck.CreatedBy != "ann" {
+		t.Errorf("Deck's creator not kept: %q", deck.CreatedBy)
+	}
+	deck.CreatedBy = "bob"
+	store.PutDeck(ctx, "D1", deck)
+	if deck, _ = store.GetDeck(ctx, "D1"); deck.CreatedBy != "ann" {
+		t.Errorf("Deck's creator changed by an update: %q", deck.CreatedBy)
+	}
+}
+
+func conformLogins(t *testing.T, ctx context.Context, store platform.DataStore) {
+	_, err := store.GetLogin(ctx, "L1")
+	expectError(t, err, platform.ErrNotFound, "reading an unknown login")
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	login := cards.Login{ID: "L1", UserName: "ann", Created: created, Expires: created.Add(cards.LOGIN_DURATION)}
+	if err := store.PutLogin(ctx, login); err != nil {
+		t.Fatalf("Failed to add login: %v", err)
+	}
+	read, err := store.GetLogin(ctx, "L1")
+	if err != nil || read.UserName != "ann" || !read.Created.Equal(created) || !read.Expires.Equal(login.Expires) {
+		t.Errorf("Login did not round trip: %+v, %v", read, err)
+	}
+
+	if err := store.DeleteLogin(ctx, "L1"); err != nil {
+		t.Errorf("Failed to delete login: %v", err)
+	}
+	_, err = store.GetLogin(ctx, "L1")
+	expectError(t, err, platform.ErrNotFound, "reading a deleted login")
+	if err := store.DeleteLogin(ctx, "L1"); err != nil {
+		t.Errorf("Deleting a login that has gone should succeed: %v", err)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 )
 
 type LogRecorder struct {
 	Entries []string
+	mu      sync.Mutex
 }
 
 func (r *LogRecorder) Debug(ctx context.Context, template string, args ...any) {
@@ -23,6 +25,8 @@ func (r *LogRecorder) Error(ctx context.Context, template string, args ...any) {
 }
 
 func (r *LogRecorder) capture(level string, template string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.Entries == nil {
 		r.Entries = make([]string, 0)
 	}
@@ -30,6 +34,8 @@ func (r *LogRecorder) capture(level string, template string, args ...any) {
 }
 
 func (r *LogRecorder) HasEntryWithPrefix(prefix string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.Entries == nil {
 		return false
 	}
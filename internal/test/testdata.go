@@ -37,7 +37,10 @@ func SetupTestData(ctx context.Context, store platform.DataStore, logs platform.
 
 But [links](http://some.bad.site/) are disabled`, Hint: "Formatting"})
 
-	store.PutDeck(ctx, testDeck.ID, testDeck)
+	if err := store.PutDeck(ctx, testDeck.ID, testDeck); err != nil {
+		logs.Error(ctx, "Failed to create test data: %v", err)
+		return
+	}
 
 	logs.Debug(ctx, "Test data created in %s", store.Summary())
 }
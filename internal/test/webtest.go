@@ -21,6 +21,7 @@ type WebTest struct {
 	method   string
 	success  bool
 	router   mux.Router
+	Cookies  []*http.Cookie // sent with each request
 }
 
 func NewWebTest(t *testing.T, router mux.Router) WebTest {
@@ -36,7 +37,7 @@ func (wt *WebTest) SendGet(path string) {
 	wt.method = http.MethodGet
 	wt.path = path
 	wt.Request = httptest.NewRequest(wt.method, wt.path, nil)
-	wt.router.ServeHTTP(wt.Response, wt.Request)
+	wt.send()
 }
 
 func (wt *WebTest) SendPost(path string, fields map[string]string) {
@@ -45,6 +46,13 @@ func (wt *WebTest) SendPost(path string, fields map[string]string) {
 	body := formPostBody(fields)
 	wt.Request = httptest.NewRequest(wt.method, wt.path, &body)
 	wt.Request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	wt.send()
+}
+
+func (wt *WebTest) send() {
+	for _, cookie := range wt.Cookies {
+		wt.Request.AddCookie(cookie)
+	}
 	wt.router.ServeHTTP(wt.Response, wt.Request)
 }
 
@@ -74,6 +82,13 @@ func (wt *WebTest) AssertSuccess() {
 	}
 }
 
+func (wt *WebTest) AssertStatus(expectedCode int) {
+	if wt.Response.Code != expectedCode {
+		wt.success = false
+		wt.t.Errorf("Unexpected response code (%d != %d) for path %s", wt.Response.Code, expectedCode, wt.path)
+	}
+}
+
 func (wt *WebTest) AssertRedirectTo(expectedTarget string) {
 	if wt.Response.Code != http.StatusSeeOther {
 		wt.success = false